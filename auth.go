@@ -0,0 +1,214 @@
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+)
+
+// Credential is a secret attached to every request sent to the gateway
+type Credential struct {
+	// Token is the secret value, for example an API key or an access token.
+	Token string
+
+	// Scheme is the authorization scheme placed before the token.
+	// Defaults to "Bearer"; set it to "-" to send the token as is.
+	Scheme string
+
+	// Header is the request header carrying the credential. Defaults to "Authorization".
+	Header string
+
+	// ExpiresAt is the time after which the credential must be refreshed.
+	// The zero value means the credential does not expire.
+	ExpiresAt time.Time
+}
+
+// String hides the token so credentials can be logged safely
+func (c Credential) String() string {
+	return fmt.Sprintf("%s: [REDACTED]", c.header())
+}
+
+// GoString hides the token when the credential is printed with %#v
+func (c Credential) GoString() string {
+	return c.String()
+}
+
+func (c Credential) header() string {
+	if c.Header == "" {
+		return "Authorization"
+	}
+	return c.Header
+}
+
+func (c Credential) value() string {
+	switch c.Scheme {
+	case "":
+		return "Bearer " + c.Token
+	case "-":
+		return c.Token
+	default:
+		return c.Scheme + " " + c.Token
+	}
+}
+
+// expired reports whether the credential expires within the given leeway
+func (c Credential) expired(now time.Time, leeway time.Duration) bool {
+	return !c.ExpiresAt.IsZero() && !now.Add(leeway).Before(c.ExpiresAt)
+}
+
+// CredentialProvider supplies the credentials used to authenticate against the gateway
+type CredentialProvider interface {
+	// Credential returns a currently valid credential
+	Credential(ctx context.Context) (Credential, error)
+
+	// Invalidate discards a credential that the gateway rejected with a 401
+	// response, so that the next call to Credential obtains a fresh one.
+	Invalidate(cred Credential)
+}
+
+// staticCredentialProvider always returns the same credential
+type staticCredentialProvider struct {
+	cred Credential
+}
+
+// StaticCredentials returns a provider that always returns cred
+func StaticCredentials(cred Credential) CredentialProvider {
+	return &staticCredentialProvider{cred: cred}
+}
+
+func (p *staticCredentialProvider) Credential(ctx context.Context) (Credential, error) {
+	return p.cred, nil
+}
+
+func (p *staticCredentialProvider) Invalidate(cred Credential) {}
+
+// RefreshFunc obtains a new credential, for example from an OAuth token endpoint
+type RefreshFunc func(ctx context.Context) (Credential, error)
+
+// refreshingCredentialProvider caches a credential until it expires or is invalidated
+type refreshingCredentialProvider struct {
+	refresh RefreshFunc
+	leeway  time.Duration
+
+	mu     sync.Mutex
+	cached *Credential
+}
+
+// NewRefreshingCredentialProvider returns a provider that caches the credential
+// returned by refresh and obtains a new one when it is about to expire, within
+// leeway, or after the gateway rejected it.
+func NewRefreshingCredentialProvider(refresh RefreshFunc, leeway time.Duration) CredentialProvider {
+	return &refreshingCredentialProvider{refresh: refresh, leeway: leeway}
+}
+
+func (p *refreshingCredentialProvider) Credential(ctx context.Context) (Credential, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cached != nil && !p.cached.expired(time.Now(), p.leeway) {
+		return *p.cached, nil
+	}
+
+	cred, err := p.refresh(ctx)
+	if err != nil {
+		return Credential{}, err
+	}
+	p.cached = &cred
+	return cred, nil
+}
+
+func (p *refreshingCredentialProvider) Invalidate(cred Credential) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Only drop the cached credential if no other request refreshed it already
+	if p.cached != nil && p.cached.Token == cred.Token {
+		p.cached = nil
+	}
+}
+
+// WithAPIKey authenticates requests with a TensorZero API key, sent as a bearer token
+func WithAPIKey(apiKey string) HTTPGatewayOption {
+	return WithCredentialProvider(StaticCredentials(Credential{Token: apiKey}))
+}
+
+// WithBearerToken is an alias of WithAPIKey for static bearer tokens that are
+// not TensorZero API keys, for example one issued for an authenticating proxy
+// in front of the gateway. Both send "Authorization: Bearer <token>".
+func WithBearerToken(token string) HTTPGatewayOption {
+	return WithAPIKey(token)
+}
+
+// WithCredentialProvider authenticates requests with credentials obtained from provider.
+// When the gateway answers with 401, the credential is invalidated and the
+// request is repeated once if the provider then returns a different credential.
+// Static credentials are not sent twice.
+func WithCredentialProvider(provider CredentialProvider) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.credentials = provider
+	}
+}
+
+// renewCredential invalidates a credential rejected by the gateway and reports
+// whether the provider returns a different one to repeat the request with
+func (g *httpGateway) renewCredential(ctx context.Context, rejected Credential) bool {
+	g.credentials.Invalidate(rejected)
+	cred, err := g.credentials.Credential(ctx)
+	return err == nil && (cred.header() != rejected.header() || cred.value() != rejected.value())
+}
+
+// authorize attaches the current credential to the request
+func (g *httpGateway) authorize(ctx context.Context, httpReq *http.Request) (*Credential, error) {
+	if g.credentials == nil {
+		return nil, nil
+	}
+
+	cred, err := g.credentials.Credential(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain credentials: %w", err)
+	}
+
+	httpReq.Header.Set(cred.header(), cred.value())
+	return &cred, nil
+}
+
+// redactCredential removes the token from an error returned for a request
+// that carried the credential
+func redactCredential(err error, cred *Credential) error {
+	if err == nil || cred == nil || cred.Token == "" {
+		return err
+	}
+
+	var tzErr *tzerrors.TensorZeroError
+	if errors.As(err, &tzErr) {
+		tzErr.Message = strings.ReplaceAll(tzErr.Message, cred.Token, redacted)
+		tzErr.Text = strings.ReplaceAll(tzErr.Text, cred.Token, redacted)
+		return err
+	}
+
+	if strings.Contains(err.Error(), cred.Token) {
+		return &redactedError{err: err, token: cred.Token}
+	}
+	return err
+}
+
+// redactedError hides a token in the message of an error while keeping its
+// chain, so that errors.Is and errors.As still see the underlying error
+type redactedError struct {
+	err   error
+	token string
+}
+
+func (e *redactedError) Error() string {
+	return strings.ReplaceAll(e.err.Error(), e.token, redacted)
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
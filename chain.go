@@ -0,0 +1,343 @@
+package tensorzero
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/denkhaus/tensorzero/datapoint"
+	"github.com/denkhaus/tensorzero/evaluation"
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/google/uuid"
+)
+
+// Call describes a single Gateway operation passing through an interceptor chain.
+//
+// Request holds the typed request of the operation:
+//   - OperationInference, OperationInferenceStream: *inference.InferenceRequest
+//   - OperationFeedback: *feedback.Request
+//   - OperationDynamicEvaluationRun: *evaluation.RunRequest
+//   - OperationDynamicEvaluationRunEpisode: *evaluation.EpisodeRequest
+//   - OperationBulkInsertDatapoints: *BulkInsertDatapointsRequest
+//   - OperationDeleteDatapoint: *DeleteDatapointRequest
+//   - OperationListDatapoints: *datapoint.ListDatapointsRequest
+//   - OperationListInferences: *inference.ListInferencesRequest
+//   - OperationClose: nil
+type Call struct {
+	Operation Operation
+	Request   interface{}
+
+	// endpoint is the path template the gateway called, set once the wrapped
+	// gateway returned
+	endpoint string
+}
+
+// Invoker executes a call and returns its typed response:
+//   - OperationInference: inference.InferenceResponse
+//   - OperationInferenceStream: *InferenceStreamResult
+//   - OperationFeedback: *feedback.Response
+//   - OperationDynamicEvaluationRun: *evaluation.RunResponse
+//   - OperationDynamicEvaluationRunEpisode: *evaluation.EpisodeResponse
+//   - OperationBulkInsertDatapoints: []uuid.UUID
+//   - OperationListDatapoints: []datapoint.Datapoint
+//   - OperationListInferences: []inference.StoredInference
+//   - OperationDeleteDatapoint, OperationClose: nil
+type Invoker func(ctx context.Context, call *Call) (interface{}, error)
+
+// Interceptor wraps every operation of a Gateway. It may inspect or modify the
+// request, call next to continue the chain, and inspect or replace the response
+// or error. Streams can be observed chunk by chunk with ObserveStream.
+type Interceptor func(ctx context.Context, call *Call, next Invoker) (interface{}, error)
+
+// BulkInsertDatapointsRequest is the request of OperationBulkInsertDatapoints
+type BulkInsertDatapointsRequest struct {
+	DatasetName string
+	Datapoints  []datapoint.DatapointInsert
+}
+
+// DeleteDatapointRequest is the request of OperationDeleteDatapoint
+type DeleteDatapointRequest struct {
+	DatasetName string
+	DatapointID uuid.UUID
+}
+
+// InferenceStreamResult is the response of OperationInferenceStream
+type InferenceStreamResult struct {
+	Chunks <-chan inference.InferenceChunk
+	Errors <-chan error
+}
+
+// ObserveStream returns a stream that forwards all chunks and errors of s.
+// onChunk is called for every chunk and onDone once the stream has ended with
+// the error that terminated it, or nil. Either callback may be nil.
+func ObserveStream(ctx context.Context, s *InferenceStreamResult, onChunk func(inference.InferenceChunk), onDone func(error)) *InferenceStreamResult {
+	chunkCh := make(chan inference.InferenceChunk, cap(s.Chunks))
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(chunkCh)
+		defer close(errCh)
+
+		var err error
+		defer func() {
+			if onDone != nil {
+				onDone(err)
+			}
+		}()
+
+		for chunk := range s.Chunks {
+			if onChunk != nil {
+				onChunk(chunk)
+			}
+			select {
+			case chunkCh <- chunk:
+			case <-ctx.Done():
+				err = ctx.Err()
+				errCh <- err
+				return
+			}
+		}
+
+		if err = <-s.Errors; err != nil {
+			errCh <- err
+		}
+	}()
+
+	return &InferenceStreamResult{Chunks: chunkCh, Errors: errCh}
+}
+
+// chainGateway routes every Gateway method through a chain of interceptors
+type chainGateway struct {
+	next   Gateway
+	invoke Invoker
+}
+
+var (
+	_ Gateway       = (*chainGateway)(nil)
+	_ Streamer      = (*chainGateway)(nil)
+	_ HealthChecker = (*chainGateway)(nil)
+)
+
+// Chain wraps gw with the given interceptors. The first interceptor is the
+// outermost one and sees every call first:
+//
+//	client := tensorzero.Chain(gw, logging, metrics, tagInjector)
+//
+// The returned gateway implements Streamer and HealthChecker. Health and Status
+// fail with errors.ErrUnsupported if gw does not implement HealthChecker.
+func Chain(gw Gateway, interceptors ...Interceptor) Gateway {
+	g := &chainGateway{next: gw}
+	g.invoke = g.dispatch
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], g.invoke
+		g.invoke = func(ctx context.Context, call *Call) (interface{}, error) {
+			return interceptor(ctx, call, next)
+		}
+	}
+	return g
+}
+
+// dispatch calls the wrapped gateway at the end of the chain
+func (g *chainGateway) dispatch(ctx context.Context, call *Call) (interface{}, error) {
+	defer func() { call.endpoint = gatewayEndpoint(g.next, call.Operation) }()
+
+	switch call.Operation {
+	case OperationInference:
+		req, err := callRequest[*inference.InferenceRequest](call)
+		if err != nil {
+			return nil, err
+		}
+		return g.next.Inference(ctx, req)
+	case OperationInferenceStream:
+		req, err := callRequest[*inference.InferenceRequest](call)
+		if err != nil {
+			return nil, err
+		}
+		chunks, errs := g.next.InferenceStream(ctx, req)
+		return &InferenceStreamResult{Chunks: chunks, Errors: errs}, nil
+	case OperationFeedback:
+		req, err := callRequest[*feedback.Request](call)
+		if err != nil {
+			return nil, err
+		}
+		return g.next.Feedback(ctx, req)
+	case OperationDynamicEvaluationRun:
+		req, err := callRequest[*evaluation.RunRequest](call)
+		if err != nil {
+			return nil, err
+		}
+		return g.next.DynamicEvaluationRun(ctx, req)
+	case OperationDynamicEvaluationRunEpisode:
+		req, err := callRequest[*evaluation.EpisodeRequest](call)
+		if err != nil {
+			return nil, err
+		}
+		return g.next.DynamicEvaluationRunEpisode(ctx, req)
+	case OperationBulkInsertDatapoints:
+		req, err := callRequest[*BulkInsertDatapointsRequest](call)
+		if err != nil {
+			return nil, err
+		}
+		return g.next.BulkInsertDatapoints(ctx, req.DatasetName, req.Datapoints)
+	case OperationDeleteDatapoint:
+		req, err := callRequest[*DeleteDatapointRequest](call)
+		if err != nil {
+			return nil, err
+		}
+		return nil, g.next.DeleteDatapoint(ctx, req.DatasetName, req.DatapointID)
+	case OperationListDatapoints:
+		req, err := callRequest[*datapoint.ListDatapointsRequest](call)
+		if err != nil {
+			return nil, err
+		}
+		return g.next.ListDatapoints(ctx, req)
+	case OperationListInferences:
+		req, err := callRequest[*inference.ListInferencesRequest](call)
+		if err != nil {
+			return nil, err
+		}
+		return g.next.ListInferences(ctx, req)
+	case OperationHealth:
+		checker, err := healthChecker(g.next)
+		if err != nil {
+			return nil, err
+		}
+		return checker.Health(ctx)
+	case OperationStatus:
+		checker, err := healthChecker(g.next)
+		if err != nil {
+			return nil, err
+		}
+		return checker.Status(ctx)
+	case OperationClose:
+		return nil, g.next.Close()
+	default:
+		return nil, fmt.Errorf("unsupported operation: %s", call.Operation)
+	}
+}
+
+// callRequest asserts the request type of a call, which interceptors may have replaced
+func callRequest[T any](call *Call) (T, error) {
+	req, ok := call.Request.(T)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("interceptor passed %T for operation %s, expected %T", call.Request, call.Operation, zero)
+	}
+	return req, nil
+}
+
+// invokeTyped runs a call through the chain and asserts the response type
+func invokeTyped[T any](ctx context.Context, g *chainGateway, op Operation, req interface{}) (T, error) {
+	var zero T
+	resp, err := g.invoke(ctx, &Call{Operation: op, Request: req})
+	if err != nil {
+		return zero, err
+	}
+	if resp == nil {
+		return zero, nil
+	}
+	typed, ok := resp.(T)
+	if !ok {
+		return zero, fmt.Errorf("interceptor returned %T for operation %s", resp, op)
+	}
+	return typed, nil
+}
+
+// Inference makes an inference request
+func (g *chainGateway) Inference(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error) {
+	return invokeTyped[inference.InferenceResponse](ctx, g, OperationInference, req)
+}
+
+// InferenceStream makes a streaming inference request
+func (g *chainGateway) InferenceStream(ctx context.Context, req *inference.InferenceRequest) (<-chan inference.InferenceChunk, <-chan error) {
+	result, err := invokeTyped[*InferenceStreamResult](ctx, g, OperationInferenceStream, req)
+	if err == nil && result == nil {
+		err = fmt.Errorf("interceptor returned no stream for operation %s", OperationInferenceStream)
+	}
+	if err != nil {
+		chunkCh := make(chan inference.InferenceChunk)
+		errCh := make(chan error, 1)
+		errCh <- err
+		close(chunkCh)
+		close(errCh)
+		return chunkCh, errCh
+	}
+	return result.Chunks, result.Errors
+}
+
+// Stream makes a streaming inference request through the chain, so that
+// interceptors observe it as OperationInferenceStream. It returns once the
+// first chunk arrived, or with the error of a request that failed before.
+func (g *chainGateway) Stream(ctx context.Context, req *inference.InferenceRequest) (*Stream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	chunks, errs := g.InferenceStream(ctx, req)
+	return openChannels(chunks, errs, cancel)
+}
+
+// Feedback sends feedback
+func (g *chainGateway) Feedback(ctx context.Context, req *feedback.Request) (*feedback.Response, error) {
+	return invokeTyped[*feedback.Response](ctx, g, OperationFeedback, req)
+}
+
+// DynamicEvaluationRun creates a dynamic evaluation run
+func (g *chainGateway) DynamicEvaluationRun(ctx context.Context, req *evaluation.RunRequest) (*evaluation.RunResponse, error) {
+	return invokeTyped[*evaluation.RunResponse](ctx, g, OperationDynamicEvaluationRun, req)
+}
+
+// DynamicEvaluationRunEpisode creates a dynamic evaluation run episode
+func (g *chainGateway) DynamicEvaluationRunEpisode(ctx context.Context, req *evaluation.EpisodeRequest) (*evaluation.EpisodeResponse, error) {
+	return invokeTyped[*evaluation.EpisodeResponse](ctx, g, OperationDynamicEvaluationRunEpisode, req)
+}
+
+// BulkInsertDatapoints inserts multiple datapoints
+func (g *chainGateway) BulkInsertDatapoints(ctx context.Context, datasetName string, datapoints []datapoint.DatapointInsert) ([]uuid.UUID, error) {
+	return invokeTyped[[]uuid.UUID](ctx, g, OperationBulkInsertDatapoints, &BulkInsertDatapointsRequest{
+		DatasetName: datasetName,
+		Datapoints:  datapoints,
+	})
+}
+
+// DeleteDatapoint deletes a datapoint
+func (g *chainGateway) DeleteDatapoint(ctx context.Context, datasetName string, datapointID uuid.UUID) error {
+	_, err := g.invoke(ctx, &Call{Operation: OperationDeleteDatapoint, Request: &DeleteDatapointRequest{
+		DatasetName: datasetName,
+		DatapointID: datapointID,
+	}})
+	return err
+}
+
+// ListDatapoints lists datapoints
+func (g *chainGateway) ListDatapoints(ctx context.Context, req *datapoint.ListDatapointsRequest) ([]datapoint.Datapoint, error) {
+	return invokeTyped[[]datapoint.Datapoint](ctx, g, OperationListDatapoints, req)
+}
+
+// ListInferences lists stored inferences with filtering and ordering
+func (g *chainGateway) ListInferences(ctx context.Context, req *inference.ListInferencesRequest) ([]inference.StoredInference, error) {
+	return invokeTyped[[]inference.StoredInference](ctx, g, OperationListInferences, req)
+}
+
+// Health checks whether the gateway is serving requests
+func (g *chainGateway) Health(ctx context.Context) (*HealthResponse, error) {
+	return invokeTyped[*HealthResponse](ctx, g, OperationHealth, nil)
+}
+
+// Status returns the gateway status and version
+func (g *chainGateway) Status(ctx context.Context) (*StatusResponse, error) {
+	return invokeTyped[*StatusResponse](ctx, g, OperationStatus, nil)
+}
+
+// WaitUntilReady polls the health endpoint through the chain until the gateway is ready
+func (g *chainGateway) WaitUntilReady(ctx context.Context) (*StatusResponse, error) {
+	return waitUntilReady(ctx, g)
+}
+
+// Close closes the wrapped gateway
+func (g *chainGateway) Close() error {
+	_, err := g.invoke(context.Background(), &Call{Operation: OperationClose})
+	return err
+}
+
+// endpoint returns the path template the wrapped gateway calls for op
+func (g *chainGateway) endpoint(op Operation) string {
+	return gatewayEndpoint(g.next, op)
+}
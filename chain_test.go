@@ -0,0 +1,255 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/denkhaus/tensorzero/datapoint"
+	"github.com/denkhaus/tensorzero/evaluation"
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newChainMockGateway() *MockGateway {
+	return &MockGateway{
+		InferenceFn: func(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error) {
+			return &inference.ChatInferenceResponse{VariantName: "v1"}, nil
+		},
+		InferenceStreamFn: func(ctx context.Context, req *inference.InferenceRequest) (<-chan inference.InferenceChunk, <-chan error) {
+			chunks := make(chan inference.InferenceChunk, 2)
+			errs := make(chan error, 1)
+			chunks <- &inference.ChatChunk{VariantName: "v1"}
+			chunks <- &inference.ChatChunk{VariantName: "v1"}
+			close(chunks)
+			close(errs)
+			return chunks, errs
+		},
+		FeedbackFn: func(ctx context.Context, req *feedback.Request) (*feedback.Response, error) {
+			return &feedback.Response{FeedbackID: uuid.New()}, nil
+		},
+		DynamicEvaluationRunFn: func(ctx context.Context, req *evaluation.RunRequest) (*evaluation.RunResponse, error) {
+			return &evaluation.RunResponse{RunID: uuid.New()}, nil
+		},
+		DynamicEvaluationRunEpisodeFn: func(ctx context.Context, req *evaluation.EpisodeRequest) (*evaluation.EpisodeResponse, error) {
+			return &evaluation.EpisodeResponse{EpisodeID: uuid.New()}, nil
+		},
+		BulkInsertDatapointsFn: func(ctx context.Context, datasetName string, datapoints []datapoint.DatapointInsert) ([]uuid.UUID, error) {
+			return []uuid.UUID{uuid.New()}, nil
+		},
+		DeleteDatapointFn: func(ctx context.Context, datasetName string, datapointID uuid.UUID) error {
+			return nil
+		},
+		ListDatapointsFn: func(ctx context.Context, req *datapoint.ListDatapointsRequest) ([]datapoint.Datapoint, error) {
+			return []datapoint.Datapoint{{DatasetName: req.DatasetName}}, nil
+		},
+		ListInferencesFn: func(ctx context.Context, req *inference.ListInferencesRequest) ([]inference.StoredInference, error) {
+			return []inference.StoredInference{{FunctionName: "f"}}, nil
+		},
+		HealthFn: func(ctx context.Context) (*HealthResponse, error) {
+			return &HealthResponse{Gateway: "ok"}, nil
+		},
+		StatusFn: func(ctx context.Context) (*StatusResponse, error) {
+			return &StatusResponse{Status: "ok", Version: "2025.5.0"}, nil
+		},
+		CloseFn: func() error {
+			return nil
+		},
+	}
+}
+
+func TestChainSeesEveryOperation(t *testing.T) {
+	var ops []Operation
+	recorder := func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		ops = append(ops, call.Operation)
+		return next(ctx, call)
+	}
+
+	gw := Chain(newChainMockGateway(), recorder)
+	ctx := context.Background()
+
+	_, err := gw.Inference(ctx, &inference.InferenceRequest{})
+	require.NoError(t, err)
+	chunks, errs := gw.InferenceStream(ctx, &inference.InferenceRequest{})
+	for range chunks {
+	}
+	require.NoError(t, <-errs)
+	_, err = gw.Feedback(ctx, &feedback.Request{})
+	require.NoError(t, err)
+	_, err = gw.DynamicEvaluationRun(ctx, &evaluation.RunRequest{})
+	require.NoError(t, err)
+	_, err = gw.DynamicEvaluationRunEpisode(ctx, &evaluation.EpisodeRequest{})
+	require.NoError(t, err)
+	ids, err := gw.BulkInsertDatapoints(ctx, "ds", nil)
+	require.NoError(t, err)
+	assert.Len(t, ids, 1)
+	require.NoError(t, gw.DeleteDatapoint(ctx, "ds", uuid.New()))
+	datapoints, err := gw.ListDatapoints(ctx, &datapoint.ListDatapointsRequest{DatasetName: "ds"})
+	require.NoError(t, err)
+	assert.Equal(t, "ds", datapoints[0].DatasetName)
+	inferences, err := gw.ListInferences(ctx, &inference.ListInferencesRequest{})
+	require.NoError(t, err)
+	assert.Len(t, inferences, 1)
+	status, err := gw.(HealthChecker).WaitUntilReady(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "2025.5.0", status.Version)
+	require.NoError(t, gw.Close())
+
+	assert.Equal(t, []Operation{
+		OperationInference,
+		OperationInferenceStream,
+		OperationFeedback,
+		OperationDynamicEvaluationRun,
+		OperationDynamicEvaluationRunEpisode,
+		OperationBulkInsertDatapoints,
+		OperationDeleteDatapoint,
+		OperationListDatapoints,
+		OperationListInferences,
+		OperationHealth,
+		OperationStatus,
+		OperationClose,
+	}, ops)
+}
+
+func TestChainOrderAndTypedValues(t *testing.T) {
+	var order []string
+	named := func(name string) Interceptor {
+		return func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+			order = append(order, name+":before")
+			resp, err := next(ctx, call)
+			order = append(order, name+":after")
+			return resp, err
+		}
+	}
+	tagInjector := func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		if req, ok := call.Request.(*inference.InferenceRequest); ok {
+			req.Tags = map[string]string{"team": "search"}
+		}
+		return next(ctx, call)
+	}
+
+	mock := newChainMockGateway()
+	var seenTags map[string]string
+	mock.InferenceFn = func(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error) {
+		seenTags = req.Tags
+		return &inference.ChatInferenceResponse{VariantName: "v1"}, nil
+	}
+
+	var seenResponse interface{}
+	observer := func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		resp, err := next(ctx, call)
+		seenResponse = resp
+		return resp, err
+	}
+
+	gw := Chain(mock, named("outer"), named("inner"), tagInjector, observer)
+	resp, err := gw.Inference(context.Background(), &inference.InferenceRequest{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"outer:before", "inner:before", "inner:after", "outer:after"}, order)
+	assert.Equal(t, map[string]string{"team": "search"}, seenTags)
+	assert.Same(t, resp, seenResponse)
+	assert.IsType(t, &inference.ChatInferenceResponse{}, seenResponse)
+}
+
+func TestChainShortCircuitAndErrors(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	blocker := func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		return nil, errBlocked
+	}
+
+	gw := Chain(newChainMockGateway(), blocker)
+	_, err := gw.Feedback(context.Background(), &feedback.Request{})
+	assert.ErrorIs(t, err, errBlocked)
+
+	chunks, errs := gw.InferenceStream(context.Background(), &inference.InferenceRequest{})
+	_, ok := <-chunks
+	assert.False(t, ok)
+	assert.ErrorIs(t, <-errs, errBlocked)
+
+	wrongType := func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		return "not a response", nil
+	}
+	gw = Chain(newChainMockGateway(), wrongType)
+	_, err = gw.Inference(context.Background(), &inference.InferenceRequest{})
+	assert.Error(t, err)
+
+	// Requests of the wrong type fail instead of panicking
+	wrongRequest := func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		call.Request = "not a request"
+		return next(ctx, call)
+	}
+	gw = Chain(newChainMockGateway(), wrongRequest)
+	_, err = gw.Inference(context.Background(), &inference.InferenceRequest{})
+	assert.ErrorContains(t, err, "expected *inference.InferenceRequest")
+	err = gw.DeleteDatapoint(context.Background(), "dataset", uuid.New())
+	assert.Error(t, err)
+}
+
+func TestChainObserveStream(t *testing.T) {
+	var chunkCount int
+	var doneErr error
+	done := make(chan struct{})
+	observer := func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		resp, err := next(ctx, call)
+		if err != nil || call.Operation != OperationInferenceStream {
+			return resp, err
+		}
+		return ObserveStream(ctx, resp.(*InferenceStreamResult),
+			func(chunk inference.InferenceChunk) { chunkCount++ },
+			func(err error) { doneErr = err; close(done) },
+		), nil
+	}
+
+	mock := newChainMockGateway()
+	streamErr := errors.New("stream broke")
+	mock.InferenceStreamFn = func(ctx context.Context, req *inference.InferenceRequest) (<-chan inference.InferenceChunk, <-chan error) {
+		chunks := make(chan inference.InferenceChunk, 1)
+		errs := make(chan error, 1)
+		chunks <- &inference.JsonChunk{Raw: "{"}
+		errs <- streamErr
+		close(chunks)
+		close(errs)
+		return chunks, errs
+	}
+
+	gw := Chain(mock, observer)
+	chunks, errs := gw.InferenceStream(context.Background(), &inference.InferenceRequest{})
+
+	var received []inference.InferenceChunk
+	for chunk := range chunks {
+		received = append(received, chunk)
+	}
+	assert.ErrorIs(t, <-errs, streamErr)
+	<-done
+
+	assert.Len(t, received, 1)
+	assert.Equal(t, 1, chunkCount)
+	assert.ErrorIs(t, doneErr, streamErr)
+}
+
+func TestChainOptionalInterfaces(t *testing.T) {
+	// Only the methods of Gateway are visible through the embedded interface
+	plain := struct{ Gateway }{newChainMockGateway()}
+	_, ok := Gateway(plain).(HealthChecker)
+	require.False(t, ok)
+
+	gw := Chain(plain)
+	_, err := gw.(HealthChecker).Health(context.Background())
+	assert.ErrorIs(t, err, errors.ErrUnsupported)
+
+	// Streams of gateways without Stream are adapted from InferenceStream
+	stream, err := OpenStream(context.Background(), plain, &inference.InferenceRequest{})
+	require.NoError(t, err)
+	count := 0
+	for _, err := range stream.All() {
+		require.NoError(t, err)
+		count++
+	}
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,363 @@
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/metrics"
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets all calls through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all calls until the open timeout has passed
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe calls through
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitKey identifies a circuit: one per gateway endpoint and function name,
+// and per replica for the replicas of a pool gateway
+type CircuitKey struct {
+	// Replica is the base URL of the replica of a pool gateway and empty for
+	// other gateways.
+	Replica string
+
+	// Endpoint is the endpoint template, for example "/inference" or
+	// "/datasets/{dataset_name}/datapoints".
+	Endpoint string
+
+	// Function is the function name of inference calls and empty otherwise.
+	Function string
+}
+
+func (k CircuitKey) String() string {
+	if k.Function == "" {
+		return k.Replica + k.Endpoint
+	}
+	return k.Replica + k.Endpoint + " " + k.Function
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker. Zero values use the defaults.
+type CircuitBreakerConfig struct {
+	// ConsecutiveFailures trips the circuit after this many failures in a row. Defaults to 5.
+	ConsecutiveFailures int
+
+	// FailureRate trips the circuit when the share of failed calls (0 to 1)
+	// within Window reaches it. Zero disables the rate threshold.
+	FailureRate float64
+
+	// MinRequests is the number of calls within Window required before
+	// FailureRate is evaluated. Defaults to 10.
+	MinRequests int
+
+	// Window is the interval over which the failure rate is measured. Defaults to one minute.
+	Window time.Duration
+
+	// OpenTimeout is how long the circuit stays open before probe calls are
+	// let through. Defaults to 30 seconds.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of concurrent probe calls allowed in the
+	// half-open state. Defaults to 1.
+	HalfOpenRequests int
+
+	// IsFailure decides whether an error counts as a failure. Defaults to
+	// transport errors, 5xx and 429 responses; canceled calls never count.
+	IsFailure func(err error) bool
+
+	// OnStateChange is called whenever a circuit changes its state, for
+	// example to export the state as a metric. It is called without holding
+	// the breaker's lock, so it may call State and States, but it may be
+	// called concurrently for different circuits.
+	OnStateChange func(key CircuitKey, from, to CircuitState)
+}
+
+// CircuitOpenError is returned for calls rejected by an open circuit
+type CircuitOpenError struct {
+	Key CircuitKey
+
+	// RetryAfter is the time until the circuit lets probe calls through.
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for %s (retry after %s)", e.Key, e.RetryAfter)
+}
+
+// CircuitBreaker stops sending calls to an endpoint and function that keeps
+// failing, giving a degraded gateway or provider time to recover.
+//
+// A streaming call counts as successful once the gateway accepted it; errors
+// while reading the stream are not recorded.
+type CircuitBreaker struct {
+	config CircuitBreakerConfig
+
+	mu       sync.Mutex
+	circuits map[CircuitKey]*circuit
+}
+
+// NewCircuitBreaker creates a CircuitBreaker from config
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.ConsecutiveFailures <= 0 {
+		config.ConsecutiveFailures = 5
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = 10
+	}
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = isCircuitFailure
+	}
+	return &CircuitBreaker{config: config, circuits: make(map[CircuitKey]*circuit)}
+}
+
+// WithCircuitBreaker rejects calls to failing endpoints and functions with a
+// *CircuitOpenError instead of sending them to the gateway
+func WithCircuitBreaker(breaker *CircuitBreaker) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.breaker = breaker
+	}
+}
+
+// State returns the current state of a circuit. Unknown circuits are closed.
+func (b *CircuitBreaker) State(key CircuitKey) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		return CircuitClosed
+	}
+	return c.state
+}
+
+// States returns the state of every circuit that has seen a call
+func (b *CircuitBreaker) States() map[CircuitKey]CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	states := make(map[CircuitKey]CircuitState, len(b.circuits))
+	for key, c := range b.circuits {
+		states[key] = c.state
+	}
+	return states
+}
+
+// allow admits a call to the circuit. The returned function must be called
+// with the outcome of the call.
+func (b *CircuitBreaker) allow(ctx context.Context, key CircuitKey) (func(error), error) {
+	b.mu.Lock()
+	done, change, err := b.admit(ctx, key)
+	b.mu.Unlock()
+
+	b.notify(change)
+	return done, err
+}
+
+// admit implements allow. It must be called with b.mu held.
+func (b *CircuitBreaker) admit(ctx context.Context, key CircuitKey) (func(error), *stateChange, error) {
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+
+	now := time.Now()
+	var change *stateChange
+	if c.state == CircuitOpen {
+		if wait := c.openedAt.Add(b.config.OpenTimeout).Sub(now); wait > 0 {
+			return nil, nil, &CircuitOpenError{Key: key, RetryAfter: wait}
+		}
+		change = b.transition(key, c, CircuitHalfOpen, now)
+	}
+	if c.state == CircuitHalfOpen {
+		if c.probes >= b.config.HalfOpenRequests {
+			return nil, change, &CircuitOpenError{Key: key}
+		}
+		c.probes++
+	}
+
+	generation := c.generation
+	return func(err error) {
+		// A call abandoned by its caller says nothing about the gateway
+		canceled := err != nil && callerCanceled(ctx)
+		b.record(key, c, generation, err != nil && !canceled && b.config.IsFailure(err), canceled)
+	}, change, nil
+}
+
+// record applies the outcome of a call admitted in the given generation
+func (b *CircuitBreaker) record(key CircuitKey, c *circuit, generation uint64, failed, ignored bool) {
+	b.mu.Lock()
+	change := b.apply(key, c, generation, failed, ignored)
+	b.mu.Unlock()
+
+	b.notify(change)
+}
+
+// apply implements record. It must be called with b.mu held.
+func (b *CircuitBreaker) apply(key CircuitKey, c *circuit, generation uint64, failed, ignored bool) *stateChange {
+	// Outcomes of calls admitted before the last state change are stale
+	if generation != c.generation {
+		return nil
+	}
+
+	now := time.Now()
+	switch c.state {
+	case CircuitHalfOpen:
+		c.probes--
+		if ignored {
+			return nil
+		}
+		if failed {
+			return b.transition(key, c, CircuitOpen, now)
+		}
+		return b.transition(key, c, CircuitClosed, now)
+	case CircuitClosed:
+		if ignored {
+			return nil
+		}
+		if now.Sub(c.windowStart) >= b.config.Window {
+			c.windowStart = now
+			c.requests, c.failures = 0, 0
+		}
+		c.requests++
+		if !failed {
+			c.consecutive = 0
+			return nil
+		}
+		c.failures++
+		c.consecutive++
+
+		rateTripped := b.config.FailureRate > 0 && c.requests >= b.config.MinRequests &&
+			float64(c.failures)/float64(c.requests) >= b.config.FailureRate
+		if c.consecutive >= b.config.ConsecutiveFailures || rateTripped {
+			return b.transition(key, c, CircuitOpen, now)
+		}
+	}
+	return nil
+}
+
+// stateChange is a transition to report to OnStateChange once b.mu is released
+type stateChange struct {
+	key      CircuitKey
+	from, to CircuitState
+}
+
+// notify reports a transition collected under b.mu. It must be called without holding b.mu.
+func (b *CircuitBreaker) notify(change *stateChange) {
+	if change != nil && b.config.OnStateChange != nil {
+		b.config.OnStateChange(change.key, change.from, change.to)
+	}
+}
+
+// transition moves the circuit to a new state and resets its counters. The
+// returned change must be passed to notify after b.mu is released.
+func (b *CircuitBreaker) transition(key CircuitKey, c *circuit, to CircuitState, now time.Time) *stateChange {
+	from := c.state
+	c.state = to
+	c.generation++
+	c.probes = 0
+	c.consecutive = 0
+	c.requests, c.failures = 0, 0
+	c.windowStart = now
+	if to == CircuitOpen {
+		c.openedAt = now
+	}
+
+	if from == to {
+		return nil
+	}
+	return &stateChange{key: key, from: from, to: to}
+}
+
+// CircuitMetrics returns an OnStateChange hook that counts state changes in
+// sink. A nil sink reports to metrics.Default().
+func CircuitMetrics(sink metrics.Sink) func(key CircuitKey, from, to CircuitState) {
+	if sink == nil {
+		sink = metrics.Default()
+	}
+	return func(key CircuitKey, from, to CircuitState) {
+		labels := metrics.Labels{
+			metrics.LabelEndpoint: key.Endpoint,
+			metrics.LabelFunction: key.Function,
+			metrics.LabelState:    to.String(),
+		}
+		if key.Replica != "" {
+			labels[metrics.LabelReplica] = key.Replica
+		}
+		sink.Add(metrics.CircuitTransitions, labels, 1)
+	}
+}
+
+// circuit holds the state of a single circuit
+type circuit struct {
+	state      CircuitState
+	generation uint64
+	openedAt   time.Time
+	probes     int
+
+	consecutive int
+	windowStart time.Time
+	requests    int
+	failures    int
+}
+
+// isCircuitFailure reports whether err indicates a degraded gateway or provider
+func isCircuitFailure(err error) bool {
+	var tzErr *tzerrors.TensorZeroError
+	if errors.As(err, &tzErr) {
+		return tzerrors.IsRetryable(tzErr) || errors.Is(tzErr, tzerrors.ErrTimeout)
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// circuitKey returns the circuit of the request
+func (g *httpGateway) circuitKey(r *apiRequest) CircuitKey {
+	endpoint := r.endpoint
+	if endpoint == "" {
+		var ok bool
+		if endpoint, ok = operationEndpoints[r.op]; !ok {
+			endpoint = r.path
+		}
+	}
+	return CircuitKey{Replica: g.replica, Endpoint: endpoint, Function: r.function}
+}
+
+// guard admits the request to its circuit if a circuit breaker is configured
+func (g *httpGateway) guard(ctx context.Context, r *apiRequest) (func(error), error) {
+	if g.breaker == nil {
+		return func(error) {}, nil
+	}
+	return g.breaker.allow(ctx, g.circuitKey(r))
+}
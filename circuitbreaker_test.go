@@ -0,0 +1,214 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/metrics"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var feedbackKey = CircuitKey{Endpoint: "/feedback"}
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{ConsecutiveFailures: 3, OpenTimeout: time.Hour})
+	client := NewHTTPGateway(server.URL, WithCircuitBreaker(breaker))
+	req := &feedback.Request{MetricName: "rating", Value: 1.0}
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Feedback(context.Background(), req)
+		require.Error(t, err)
+	}
+	assert.Equal(t, CircuitOpen, breaker.State(feedbackKey))
+
+	_, err := client.Feedback(context.Background(), req)
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Equal(t, feedbackKey, openErr.Key)
+	assert.Greater(t, openErr.RetryAfter, time.Duration(0))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid"}`))
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{ConsecutiveFailures: 2})
+	client := NewHTTPGateway(server.URL, WithCircuitBreaker(breaker))
+
+	for i := 0; i < 5; i++ {
+		_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+		require.Error(t, err)
+	}
+	assert.Equal(t, CircuitClosed, breaker.State(feedbackKey))
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every other call fails, so the consecutive threshold is never reached
+		if atomic.AddInt32(&calls, 1)%2 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(feedbackOK))
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureRate: 0.5, MinRequests: 4, OpenTimeout: time.Hour})
+	client := NewHTTPGateway(server.URL, WithCircuitBreaker(breaker))
+
+	for i := 0; i < 4; i++ {
+		client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	}
+	assert.Equal(t, CircuitOpen, breaker.State(feedbackKey))
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(feedbackOK))
+	}))
+	defer server.Close()
+
+	var transitions []CircuitState
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         20 * time.Millisecond,
+		OnStateChange: func(key CircuitKey, from, to CircuitState) {
+			transitions = append(transitions, to)
+		},
+	})
+	client := NewHTTPGateway(server.URL, WithCircuitBreaker(breaker))
+	req := &feedback.Request{MetricName: "rating", Value: 1.0}
+
+	_, err := client.Feedback(context.Background(), req)
+	require.Error(t, err)
+	assert.Equal(t, CircuitOpen, breaker.State(feedbackKey))
+
+	// A failed probe opens the circuit again
+	time.Sleep(30 * time.Millisecond)
+	_, err = client.Feedback(context.Background(), req)
+	require.Error(t, err)
+	assert.Equal(t, CircuitOpen, breaker.State(feedbackKey))
+
+	// A successful probe closes it
+	healthy.Store(true)
+	time.Sleep(30 * time.Millisecond)
+	_, err = client.Feedback(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, CircuitClosed, breaker.State(feedbackKey))
+
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}, transitions)
+}
+
+func TestCircuitBreakerStateChangeHookReadsState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var breaker *CircuitBreaker
+	var observed []CircuitState
+	breaker = NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         time.Hour,
+		OnStateChange: func(key CircuitKey, from, to CircuitState) {
+			// The hook runs without the breaker's lock
+			observed = append(observed, breaker.State(key))
+			assert.Len(t, breaker.States(), 1)
+		},
+	})
+	client := NewHTTPGateway(server.URL, WithCircuitBreaker(breaker))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStateChange deadlocked")
+	}
+	assert.Equal(t, []CircuitState{CircuitOpen}, observed)
+}
+
+func TestCircuitBreakerPerFunction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			FunctionName string `json:"function_name"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.FunctionName == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(inferenceOK))
+	}))
+	defer server.Close()
+
+	registry := metrics.NewRegistry()
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         time.Hour,
+		OnStateChange:       CircuitMetrics(registry),
+	})
+	client := NewHTTPGateway(server.URL, WithCircuitBreaker(breaker))
+
+	_, err := client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("broken")})
+	require.Error(t, err)
+	_, err = client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[CircuitKey]CircuitState{
+		{Endpoint: "/inference", Function: "broken"}: CircuitOpen,
+		{Endpoint: "/inference", Function: "chat"}:   CircuitClosed,
+	}, breaker.States())
+	assert.Equal(t, 1.0, registry.Counter(metrics.CircuitTransitions, metrics.Labels{
+		metrics.LabelEndpoint: "/inference",
+		metrics.LabelFunction: "broken",
+		metrics.LabelState:    "open",
+	}))
+}
+
+func TestCircuitBreakerIgnoresCanceledCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{ConsecutiveFailures: 1})
+	client := NewHTTPGateway(server.URL, WithCircuitBreaker(breaker))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := client.Feedback(ctx, &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.Error(t, err)
+	assert.Equal(t, CircuitClosed, breaker.State(feedbackKey))
+}
@@ -6,40 +6,64 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/denkhaus/tensorzero/datapoint"
+	tzerrors "github.com/denkhaus/tensorzero/errors"
 	"github.com/denkhaus/tensorzero/evaluation"
 	"github.com/denkhaus/tensorzero/feedback"
 	"github.com/denkhaus/tensorzero/inference"
-	"github.com/denkhaus/tensorzero/shared"
 	"github.com/google/uuid"
 )
 
 // HTTPGateway implements Gateway using HTTP requests
 type httpGateway struct {
-	baseURL    string
-	httpClient *http.Client
-	timeout    time.Duration
+	baseURL     string
+	httpClient  *http.Client
+	timeout     time.Duration
+	retryPolicy *RetryPolicy
+	credentials CredentialProvider
+	logger      *slog.Logger
+	redactors   []RedactFunc
+	rateLimiter *RateLimiter
+	breaker     *CircuitBreaker
+	replica     string                         // base URL of a pool replica, part of its circuit keys
+	routes      sync.Map                       // Operation to the path template served by the gateway
+	version     atomic.Pointer[GatewayVersion] // reported by Status
+
+	maxEventSize      int
+	connectTimeout    time.Duration
+	firstChunkTimeout time.Duration
+	idleTimeout       time.Duration
 }
 
-// NewHTTPGateway creates a new HTTP gateway client
+var (
+	_ Streamer      = (*httpGateway)(nil)
+	_ HealthChecker = (*httpGateway)(nil)
+)
+
+// NewHTTPGateway creates a new HTTP gateway client. The returned gateway
+// implements Streamer and HealthChecker.
 func NewHTTPGateway(baseURL string, options ...HTTPGatewayOption) Gateway {
 	gateway := &httpGateway{
-		baseURL:    strings.TrimSuffix(baseURL, "/"),
-		httpClient: &http.Client{},
-		timeout:    30 * time.Second,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   &http.Client{},
+		timeout:      30 * time.Second,
+		maxEventSize: DefaultMaxEventSize,
 	}
 
 	for _, option := range options {
 		option(gateway)
 	}
 
-	if gateway.httpClient.Timeout == 0 {
-		gateway.httpClient.Timeout = gateway.timeout
+	if gateway.connectTimeout > 0 {
+		gateway.httpClient = withDialTimeout(gateway.httpClient, gateway.connectTimeout)
 	}
 
 	return gateway
@@ -48,11 +72,11 @@ func NewHTTPGateway(baseURL string, options ...HTTPGatewayOption) Gateway {
 // HTTPGatewayOption represents configuration options for HTTPGateway
 type HTTPGatewayOption func(*httpGateway)
 
-// WithTimeout sets the timeout for HTTP requests
+// WithTimeout sets the timeout of unary calls, including their retries.
+// Streams are not bounded by it; see WithFirstChunkTimeout and WithIdleTimeout.
 func WithTimeout(timeout time.Duration) HTTPGatewayOption {
 	return func(g *httpGateway) {
 		g.timeout = timeout
-		g.httpClient.Timeout = timeout
 	}
 }
 
@@ -63,259 +87,401 @@ func WithHTTPClient(client *http.Client) HTTPGatewayOption {
 	}
 }
 
-// Inference makes an inference request
-func (g *httpGateway) Inference(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error) {
-	reqBody, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// apiRequest describes a single call to the gateway. The body is kept as
+// bytes so that the request can be rebuilt for every attempt.
+type apiRequest struct {
+	op     Operation
+	method string
+	path   string
+	query  url.Values
+	body   []byte
+	accept string
+
+	// endpoint is the path template of routes resolved at runtime
+	endpoint string
+
+	// function is the function name of inference requests
+	function string
+}
+
+// newAPIRequest creates an apiRequest with a JSON encoded payload
+func newAPIRequest(op Operation, method, path string, payload interface{}) (*apiRequest, error) {
+	r := &apiRequest{op: op, method: method, path: path}
+	if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		r.body = body
 	}
+	return r, nil
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+"/inference", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// newHTTPRequest builds the HTTP request for a single attempt
+func (g *httpGateway) newHTTPRequest(ctx context.Context, r *apiRequest) (*http.Request, error) {
+	u := g.baseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
 
-	resp, err := g.httpClient.Do(httpReq)
+	httpReq, err := http.NewRequestWithContext(ctx, r.method, u, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, &shared.TensorZeroError{
-			StatusCode: resp.StatusCode,
-			Text:       string(body),
-		}
+	if r.body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
 	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	if r.accept != "" {
+		httpReq.Header.Set("Accept", r.accept)
 	}
+	injectTraceContext(ctx, httpReq.Header)
 
-	return parseInferenceResponse(body)
+	return httpReq, nil
 }
 
-// InferenceStream makes a streaming inference request
-func (g *httpGateway) InferenceStream(ctx context.Context, req *inference.InferenceRequest) (<-chan inference.InferenceChunk, <-chan error) {
-	chunkCh := make(chan inference.InferenceChunk, 10)
-	errCh := make(chan error, 1)
-
-	go func() {
-		defer close(chunkCh)
-		defer close(errCh)
-
-		// Set stream to true
-		streamReq := *req
-		streamTrue := true
-		streamReq.Stream = &streamTrue
-
-		reqBody, err := json.Marshal(streamReq)
-		if err != nil {
-			errCh <- fmt.Errorf("failed to marshal request: %w", err)
-			return
+// send performs the request, retrying according to the retry policy, and
+// returns the first successful response. The caller must close its body.
+func (g *httpGateway) send(ctx context.Context, r *apiRequest) (*http.Response, error) {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			if err := g.limitRetry(ctx, r); err != nil {
+				return nil, err
+			}
 		}
-
-		httpReq, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+"/inference", bytes.NewBuffer(reqBody))
+		done, err := g.guard(ctx, r)
 		if err != nil {
-			errCh <- fmt.Errorf("failed to create request: %w", err)
-			return
+			return nil, err
 		}
-
-		httpReq.Header.Set("Content-Type", "application/json")
-		httpReq.Header.Set("Accept", "text/event-stream")
-
-		resp, err := g.httpClient.Do(httpReq)
-		if err != nil {
-			errCh <- fmt.Errorf("failed to make request: %w", err)
-			return
+		resp, retryAfter, err := g.attempt(ctx, r)
+		done(err)
+		if err == nil {
+			return resp, nil
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			errCh <- &shared.TensorZeroError{
-				StatusCode: resp.StatusCode,
-				Text:       string(body),
-			}
-			return
+		policy := g.retryPolicy
+		if !policy.allows(r.op) || attempt >= policy.MaxAttempts || !isRetryableError(ctx, err) {
+			return nil, err
 		}
 
-		// Parse SSE stream
-		scanner := NewSSEScanner(resp.Body)
-		for scanner.Scan() {
-			event := scanner.Event()
-			if event.Data == "" {
-				continue
-			}
-
-			chunk, err := parseInferenceChunk([]byte(event.Data))
-			if err != nil {
-				errCh <- fmt.Errorf("failed to parse chunk: %w", err)
-				return
-			}
-
-			select {
-			case chunkCh <- chunk:
-			case <-ctx.Done():
-				errCh <- ctx.Err()
-				return
-			}
+		delay := policy.backoff(attempt)
+		if policy.RespectRetryAfter && retryAfter > 0 {
+			delay = retryAfter
 		}
-
-		if err := scanner.Err(); err != nil {
-			errCh <- fmt.Errorf("failed to read stream: %w", err)
+		if policy.MaxElapsed > 0 && time.Since(start)+delay > policy.MaxElapsed {
+			return nil, err
 		}
-	}()
-
-	return chunkCh, errCh
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return nil, sleepErr
+		}
+	}
 }
 
-// Feedback sends feedback
-func (g *httpGateway) Feedback(ctx context.Context, req *feedback.Request) (*feedback.Response, error) {
-	reqBody, err := json.Marshal(req)
+// attempt sends the request once and turns unsuccessful responses into errors,
+// along with the delay requested by a Retry-After header. A request rejected
+// with 401 is repeated once if the credential provider issues a new credential.
+func (g *httpGateway) attempt(ctx context.Context, r *apiRequest) (*http.Response, time.Duration, error) {
+	resp, cred, err := g.roundTrip(ctx, r)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && cred != nil && g.renewCredential(ctx, *cred) {
+		resp.Body.Close()
+		resp, cred, err = g.roundTrip(ctx, r)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, 0, redactCredential(err, cred)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+"/feedback", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return nil, retryAfter, redactCredential(newResponseError(resp), cred)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	return resp, 0, nil
+}
 
-	resp, err := g.httpClient.Do(httpReq)
+// roundTrip builds, authorizes and sends the HTTP request
+func (g *httpGateway) roundTrip(ctx context.Context, r *apiRequest) (*http.Response, *Credential, error) {
+	httpReq, err := g.newHTTPRequest(ctx, r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, &shared.TensorZeroError{
-			StatusCode: resp.StatusCode,
-			Text:       string(body),
-		}
+	cred, err := g.authorize(ctx, httpReq)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var feedbackResp feedback.Response
-	if err := json.NewDecoder(resp.Body).Decode(&feedbackResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	start := time.Now()
+	resp, err := g.httpClient.Do(httpReq)
+	g.logAttempt(ctx, r, httpReq, cred, resp, start, err)
+	if err != nil {
+		return nil, cred, g.connectTimeoutError(fmt.Errorf("failed to make request: %w", err))
 	}
 
-	return &feedbackResp, nil
+	return resp, cred, nil
 }
 
-// DynamicEvaluationRun creates a dynamic evaluation run
-func (g *httpGateway) DynamicEvaluationRun(ctx context.Context, req *evaluation.RunRequest) (*evaluation.RunResponse, error) {
-	reqBody, err := json.Marshal(req)
+// newResponseError reads and closes the body of a failed response
+func newResponseError(resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return tzerrors.ParseAPIError(resp.StatusCode, resp.Header, body)
+}
+
+// do sends the request and returns the body of the successful response
+func (g *httpGateway) do(ctx context.Context, r *apiRequest) ([]byte, error) {
+	start := time.Now()
+	release, err := g.limit(ctx, r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		g.logCall(ctx, r, start, 0, nil, nil, err)
+		return nil, err
 	}
+	defer release()
+
+	reqCtx, cancel := g.requestContext(ctx)
+	defer cancel()
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+"/dynamic_evaluation_run", bytes.NewBuffer(reqBody))
+	resp, err := g.send(reqCtx, r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		err = timeoutCause(reqCtx, err)
+		g.logCall(ctx, r, start, 0, nil, nil, err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = timeoutCause(reqCtx, fmt.Errorf("failed to read response body: %w", err))
+		g.logCall(ctx, r, start, resp.StatusCode, nil, nil, err)
+		return nil, err
+	}
 
-	resp, err := g.httpClient.Do(httpReq)
+	var ids *responseIDs
+	if g.logger != nil && g.logger.Enabled(ctx, slog.LevelInfo) {
+		// Only decode the IDs if the call is logged
+		ids = parseResponseIDs(r.op, body)
+	}
+	g.logCall(ctx, r, start, resp.StatusCode, ids, body, nil)
+	return body, nil
+}
+
+// doJSON sends the request and decodes the successful response into out
+func (g *httpGateway) doJSON(ctx context.Context, r *apiRequest, out interface{}) error {
+	body, err := g.do(ctx, r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, &shared.TensorZeroError{
-			StatusCode: resp.StatusCode,
-			Text:       string(body),
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// Inference makes an inference request
+func (g *httpGateway) Inference(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error) {
+	if req != nil {
+		if err := req.Validate(); err != nil {
+			return nil, err
 		}
 	}
 
-	var evalResp evaluation.RunResponse
-	if err := json.NewDecoder(resp.Body).Decode(&evalResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	r, err := newAPIRequest(OperationInference, "POST", "/inference", req)
+	if err != nil {
+		return nil, err
 	}
+	r.function = inferenceFunctionName(req)
 
-	return &evalResp, nil
+	body, err := g.do(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return inference.DecodeInferenceResponse(body)
 }
 
-// DynamicEvaluationRunEpisode creates a dynamic evaluation run episode
-func (g *httpGateway) DynamicEvaluationRunEpisode(ctx context.Context, req *evaluation.EpisodeRequest) (*evaluation.EpisodeResponse, error) {
-	reqBody, err := json.Marshal(req)
+// Stream makes a streaming inference request and returns the stream once the
+// gateway accepted it. The stream must be closed.
+func (g *httpGateway) Stream(ctx context.Context, req *inference.InferenceRequest) (*Stream, error) {
+	if req == nil {
+		return nil, tzerrors.NewValidationError("request", "streaming inference request is required")
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Set stream to true
+	streamReq := *req
+	streamTrue := true
+	streamReq.Stream = &streamTrue
+
+	r, err := newAPIRequest(OperationInferenceStream, "POST", "/inference", streamReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
+	r.accept = "text/event-stream"
+	r.function = inferenceFunctionName(req)
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+"/dynamic_evaluation_run_episode", bytes.NewBuffer(reqBody))
+	start := time.Now()
+	release, err := g.limit(ctx, r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		g.logCall(ctx, r, start, 0, nil, nil, err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	// Streams have no total deadline, only the first chunk and idle timeouts
+	streamCtx, watchdog, stop := g.watchStream(ctx)
 
-	resp, err := g.httpClient.Do(httpReq)
+	resp, err := g.send(streamCtx, r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
+		err = timeoutCause(streamCtx, err)
+		stop()
+		release()
+		g.logCall(ctx, r, start, 0, nil, nil, err)
+		return nil, err
+	}
+
+	reader := &chunkReader{
+		ctx:      streamCtx,
+		resp:     resp,
+		scanner:  NewSSEScanner(resp.Body, WithMaxEventSize(g.maxEventSize)),
+		watchdog: watchdog,
+	}
+	return newStream(reader.next, func(err error) {
+		stop()
+		resp.Body.Close()
+		release()
+		g.logStream(ctx, r, start, resp.StatusCode, &reader.ids, err)
+	}), nil
+}
+
+// InferenceStream makes a streaming inference request. It is a channel-based
+// shim around Stream.
+func (g *httpGateway) InferenceStream(ctx context.Context, req *inference.InferenceRequest) (<-chan inference.InferenceChunk, <-chan error) {
+	chunkCh := make(chan inference.InferenceChunk, 10)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(chunkCh)
+		defer close(errCh)
+
+		stream, err := g.Stream(ctx, req)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer stream.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, &shared.TensorZeroError{
-			StatusCode: resp.StatusCode,
-			Text:       string(body),
+		if err := stream.forward(ctx, chunkCh); err != nil {
+			errCh <- err
 		}
+	}()
+
+	return chunkCh, errCh
+}
+
+// Feedback sends feedback
+func (g *httpGateway) Feedback(ctx context.Context, req *feedback.Request) (*feedback.Response, error) {
+	r, err := newAPIRequest(OperationFeedback, "POST", "/feedback", req)
+	if err != nil {
+		return nil, err
 	}
 
-	var episodeResp evaluation.EpisodeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&episodeResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	var feedbackResp feedback.Response
+	if err := g.doJSON(ctx, r, &feedbackResp); err != nil {
+		return nil, err
 	}
 
-	return &episodeResp, nil
+	return &feedbackResp, nil
 }
 
-// BulkInsertDatapoints inserts multiple datapoints
-func (g *httpGateway) BulkInsertDatapoints(ctx context.Context, datasetName string, datapoints []datapoint.DatapointInsert) ([]uuid.UUID, error) {
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"datapoints": datapoints,
-	})
+// DynamicEvaluationRun creates a dynamic evaluation run
+func (g *httpGateway) DynamicEvaluationRun(ctx context.Context, req *evaluation.RunRequest) (*evaluation.RunResponse, error) {
+	r, err := newAPIRequest(OperationDynamicEvaluationRun, "POST", "/dynamic_evaluation_run", req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	endpoint := fmt.Sprintf("/datasets/%s/datapoints/bulk", url.PathEscape(datasetName))
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+endpoint, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	var evalResp evaluation.RunResponse
+	if err := g.doJSON(ctx, r, &evalResp); err != nil {
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	return &evalResp, nil
+}
 
-	resp, err := g.httpClient.Do(httpReq)
+// DynamicEvaluationRunEpisode creates a dynamic evaluation run episode. The
+// route of the endpoint changed between gateway releases: the newest route is
+// tried first and the route served by the gateway is remembered.
+func (g *httpGateway) DynamicEvaluationRunEpisode(ctx context.Context, req *evaluation.EpisodeRequest) (*evaluation.EpisodeResponse, error) {
+	var lastErr error
+	for _, endpoint := range g.endpoints(OperationDynamicEvaluationRunEpisode) {
+		episodeResp, err := g.dynamicEvaluationRunEpisode(ctx, endpoint, req)
+		if err == nil {
+			g.routes.Store(OperationDynamicEvaluationRunEpisode, endpoint)
+			return episodeResp, nil
+		}
+		if !isMissingRoute(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// dynamicEvaluationRunEpisode creates an episode using the given route
+func (g *httpGateway) dynamicEvaluationRunEpisode(ctx context.Context, endpoint string, req *evaluation.EpisodeRequest) (*evaluation.EpisodeResponse, error) {
+	var payload interface{} = req
+	if strings.Contains(endpoint, "{run_id}") {
+		// The run ID is part of the path and must not be repeated in the body
+		payload = struct {
+			TaskName      *string           `json:"task_name,omitempty"`
+			DatapointName *string           `json:"datapoint_name,omitempty"`
+			Tags          map[string]string `json:"tags,omitempty"`
+		}{req.TaskName, req.DatapointName, req.Tags}
+	}
+
+	r, err := newAPIRequest(OperationDynamicEvaluationRunEpisode, "POST", expandPath(endpoint, "run_id", req.RunID.String()), payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	r.endpoint = endpoint
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, &shared.TensorZeroError{
-			StatusCode: resp.StatusCode,
-			Text:       string(body),
+	var episodeResp evaluation.EpisodeResponse
+	if err := g.doJSON(ctx, r, &episodeResp); err != nil {
+		return nil, err
+	}
+
+	return &episodeResp, nil
+}
+
+// BulkInsertDatapoints inserts multiple datapoints
+func (g *httpGateway) BulkInsertDatapoints(ctx context.Context, datasetName string, datapoints []datapoint.DatapointInsert) ([]uuid.UUID, error) {
+	for _, dp := range datapoints {
+		if chat, ok := dp.(*inference.ChatDatapointInsert); ok {
+			if err := chat.ValidateToolChoice(); err != nil {
+				return nil, err
+			}
 		}
 	}
 
+	endpoint := fmt.Sprintf("/datasets/%s/datapoints/bulk", url.PathEscape(datasetName))
+	r, err := newAPIRequest(OperationBulkInsertDatapoints, "POST", endpoint, map[string]interface{}{
+		"datapoints": datapoints,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	var ids []uuid.UUID
-	if err := json.NewDecoder(resp.Body).Decode(&ids); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := g.doJSON(ctx, r, &ids); err != nil {
+		return nil, err
 	}
 
 	return ids, nil
@@ -324,38 +490,24 @@ func (g *httpGateway) BulkInsertDatapoints(ctx context.Context, datasetName stri
 // DeleteDatapoint deletes a datapoint
 func (g *httpGateway) DeleteDatapoint(ctx context.Context, datasetName string, datapointID uuid.UUID) error {
 	endpoint := fmt.Sprintf("/datasets/%s/datapoints/%s", url.PathEscape(datasetName), datapointID.String())
-	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", g.baseURL+endpoint, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := g.httpClient.Do(httpReq)
+	r, err := newAPIRequest(OperationDeleteDatapoint, "DELETE", endpoint, nil)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return &shared.TensorZeroError{
-			StatusCode: resp.StatusCode,
-			Text:       string(body),
-		}
+		return err
 	}
 
-	return nil
+	_, err = g.do(ctx, r)
+	return err
 }
 
 // ListDatapoints lists datapoints
 func (g *httpGateway) ListDatapoints(ctx context.Context, req *datapoint.ListDatapointsRequest) ([]datapoint.Datapoint, error) {
 	endpoint := fmt.Sprintf("/datasets/%s/datapoints", url.PathEscape(req.DatasetName))
-
-	u, err := url.Parse(g.baseURL + endpoint)
+	r, err := newAPIRequest(OperationListDatapoints, "GET", endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse URL: %w", err)
+		return nil, err
 	}
 
-	q := u.Query()
+	q := url.Values{}
 	if req.FunctionName != nil {
 		q.Set("function_name", *req.FunctionName)
 	}
@@ -365,30 +517,11 @@ func (g *httpGateway) ListDatapoints(ctx context.Context, req *datapoint.ListDat
 	if req.Offset != nil {
 		q.Set("offset", fmt.Sprintf("%d", *req.Offset))
 	}
-	u.RawQuery = q.Encode()
-
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := g.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, &shared.TensorZeroError{
-			StatusCode: resp.StatusCode,
-			Text:       string(body),
-		}
-	}
+	r.query = q
 
 	var datapoints []datapoint.Datapoint
-	if err := json.NewDecoder(resp.Body).Decode(&datapoints); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := g.doJSON(ctx, r, &datapoints); err != nil {
+		return nil, err
 	}
 
 	return datapoints, nil
@@ -402,36 +535,22 @@ func (g *httpGateway) Close() error {
 
 // ListInferences lists stored inferences with filtering and ordering
 func (g *httpGateway) ListInferences(ctx context.Context, req *inference.ListInferencesRequest) ([]inference.StoredInference, error) {
-	reqBody, err := json.Marshal(req)
+	r, err := newAPIRequest(OperationListInferences, "POST", "/inferences/list", req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+"/inferences/list", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := g.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	var inferences []inference.StoredInference
+	if err := g.doJSON(ctx, r, &inferences); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, &shared.TensorZeroError{
-			StatusCode: resp.StatusCode,
-			Text:       string(body),
+	if req != nil && len(req.MetricTypes) > 0 {
+		for i := range inferences {
+			if err := inferences[i].TypeMetricValues(req.MetricTypes); err != nil {
+				return nil, fmt.Errorf("inference %s: %w", inferences[i].ID, err)
+			}
 		}
 	}
 
-	var inferences []inference.StoredInference
-	if err := json.NewDecoder(resp.Body).Decode(&inferences); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
 	return inferences, nil
 }
@@ -4,13 +4,16 @@ package tensorzero
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	tzerrors "github.com/denkhaus/tensorzero/errors"
 	"github.com/denkhaus/tensorzero/inference"
 	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/tool"
 	"github.com/denkhaus/tensorzero/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -128,6 +131,12 @@ func TestInferenceWithError(t *testing.T) {
 	response, err := client.Inference(context.Background(), request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
+	assert.ErrorIs(t, err, tzerrors.ErrInvalidRequest)
+
+	var tzErr *shared.TensorZeroError
+	require.ErrorAs(t, err, &tzErr)
+	assert.Equal(t, "Invalid request", tzErr.Message)
+	assert.Equal(t, `{"error": "Invalid request"}`, tzErr.Text)
 }
 
 func TestInferenceStreamChannels(t *testing.T) {
@@ -190,6 +199,66 @@ func TestInferenceWithContextCancellation(t *testing.T) {
 	assert.Contains(t, err.Error(), "context deadline exceeded")
 }
 
+func TestInferenceValidatesToolChoice(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	req := &inference.InferenceRequest{
+		FunctionName: util.StringPtr("f"),
+		AllowedTools: []string{"search"},
+		ToolChoice:   tool.Specific("delete"),
+	}
+	client := NewHTTPGateway(server.URL)
+
+	_, err := client.Inference(context.Background(), req)
+	var validationErr *tzerrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	_, err = OpenStream(context.Background(), client, req)
+	require.ErrorAs(t, err, &validationErr)
+	assert.False(t, called)
+}
+
+func TestInferenceSendsExtraBodyAndHeaders(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"inference_id":"550e8400-e29b-41d4-a716-446655440000","episode_id":"550e8400-e29b-41d4-a716-446655440001","variant_name":"v1","content":[],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL)
+	req := &inference.InferenceRequest{
+		FunctionName: util.StringPtr("f"),
+		ExtraBody: []inference.ExtraBody{
+			inference.SetExtraBody("/reasoning_effort", "high").ForVariant("o3"),
+			inference.DeleteExtraBody("/temperature"),
+		},
+		ExtraHeaders: []inference.ExtraHeader{
+			inference.SetExtraHeader("anthropic-beta", "tools").ForProvider("anthropic"),
+		},
+	}
+	_, err := client.Inference(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"variant_name": "o3", "pointer": "/reasoning_effort", "value": "high"},
+		map[string]interface{}{"pointer": "/temperature", "delete": true},
+	}, body["extra_body"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"model_provider_name": "anthropic", "name": "anthropic-beta", "value": "tools"},
+	}, body["extra_headers"])
+
+	req.ExtraBody = []inference.ExtraBody{inference.SetExtraBody("reasoning_effort", "high")}
+	_, err = client.Inference(context.Background(), req)
+	var validationErr *tzerrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "extra_body", validationErr.Field)
+}
+
 func TestInferenceRequestValidation(t *testing.T) {
 	client := NewHTTPGateway("http://localhost:3000")
 	
@@ -209,4 +278,25 @@ func TestInferenceRequestValidation(t *testing.T) {
 	response, err = client.Inference(context.Background(), request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
-}
\ No newline at end of file
+}
+func TestListInferencesMetricTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.NotContains(t, body, "MetricTypes")
+		w.Write([]byte(`[{"type": "json", "id": "550e8400-e29b-41d4-a716-446655440000", "output": null,
+			"metric_values": {"accurate": 1, "score": 1}}]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL)
+	inferences, err := client.ListInferences(context.Background(), &inference.ListInferencesRequest{
+		MetricTypes: map[string]inference.MetricType{"accurate": inference.MetricTypeBoolean},
+	})
+	require.NoError(t, err)
+	require.Len(t, inferences, 1)
+	assert.Equal(t, map[string]inference.MetricValue{
+		"accurate": inference.BoolMetricValue(true),
+		"score":    inference.FloatMetricValue(1),
+	}, inferences[0].MetricValues)
+}
@@ -1,7 +1,12 @@
 package datapoint
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
 	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/shared"
 	"github.com/denkhaus/tensorzero/tool"
 	"github.com/google/uuid"
 )
@@ -27,8 +32,9 @@ type Datapoint struct {
 	Input inference.InferenceInput `json:"input"`
 
 	// Output contains the expected or actual output for this datapoint.
-	// For chat functions, this would be content blocks; for JSON functions,
-	// this would be the structured output object.
+	// Decoded datapoints hold []shared.ContentBlock for chat functions and
+	// inference.JsonInferenceOutput for JSON functions; use ChatOutput and
+	// JsonOutput to access it.
 	Output interface{} `json:"output"`
 
 	// DatasetName is the name of the dataset this datapoint belongs to.
@@ -52,6 +58,61 @@ type Datapoint struct {
 	IsCustom bool `json:"is_custom"`
 }
 
+// ChatOutput returns the output of a datapoint of a chat function
+func (d *Datapoint) ChatOutput() ([]shared.ContentBlock, bool) {
+	content, ok := d.Output.([]shared.ContentBlock)
+	return content, ok
+}
+
+// JsonOutput returns the output of a datapoint of a JSON function
+func (d *Datapoint) JsonOutput() (inference.JsonInferenceOutput, bool) {
+	output, ok := d.Output.(inference.JsonInferenceOutput)
+	return output, ok
+}
+
+// UnmarshalJSON decodes a datapoint. The output is decoded according to its
+// shape: a list of content blocks for chat functions, decoded with
+// shared.DecodeContentBlocks, or the output object of JSON functions.
+func (d *Datapoint) UnmarshalJSON(data []byte) error {
+	// datapoint has the fields but not the methods of Datapoint
+	type datapoint Datapoint
+	var raw struct {
+		datapoint
+		Output json.RawMessage `json:"output"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*d = Datapoint(raw.datapoint)
+	d.Output = nil
+
+	output := bytes.TrimSpace(raw.Output)
+	if len(output) == 0 || bytes.Equal(output, []byte("null")) {
+		return nil
+	}
+
+	if output[0] == '[' {
+		var blocks []json.RawMessage
+		if err := json.Unmarshal(output, &blocks); err != nil {
+			return fmt.Errorf("failed to decode chat output: %w", err)
+		}
+		content, err := shared.DecodeContentBlocks(blocks)
+		if err != nil {
+			return fmt.Errorf("failed to decode chat output: %w", err)
+		}
+		d.Output = content
+		return nil
+	}
+
+	var jsonOutput inference.JsonInferenceOutput
+	if err := json.Unmarshal(output, &jsonOutput); err != nil {
+		return fmt.Errorf("failed to decode JSON output: %w", err)
+	}
+	d.Output = jsonOutput
+	return nil
+}
+
 // ListDatapointsRequest represents a request to list datapoints from a dataset.
 // This allows you to retrieve and paginate through datapoints for analysis,
 // evaluation, or other processing needs.
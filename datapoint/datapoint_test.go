@@ -47,6 +47,30 @@ func TestDatapoint(t *testing.T) {
 	assert.True(t, dp.IsCustom)
 }
 
+func TestDatapointUnmarshalOutput(t *testing.T) {
+	var chat Datapoint
+	err := json.Unmarshal([]byte(`{"id":"`+uuid.NewString()+`","input":{},"dataset_name":"d","function_name":"f",
+		"output":[{"type":"text","text":"Hello"},{"type":"image_url","url":"https://example.com/cat.png"}]}`), &chat)
+	assert.NoError(t, err)
+	content, ok := chat.ChatOutput()
+	assert.True(t, ok)
+	assert.Equal(t, []shared.ContentBlock{shared.NewText("Hello"), shared.NewImageURL("https://example.com/cat.png")}, content)
+	_, ok = chat.JsonOutput()
+	assert.False(t, ok)
+
+	var jsonDatapoint Datapoint
+	err = json.Unmarshal([]byte(`{"id":"`+uuid.NewString()+`","input":{},"dataset_name":"d","function_name":"f",
+		"output":{"raw":"{\"a\":1}","parsed":{"a":1}}}`), &jsonDatapoint)
+	assert.NoError(t, err)
+	output, ok := jsonDatapoint.JsonOutput()
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"a": 1.0}, output.Parsed)
+
+	var empty Datapoint
+	assert.NoError(t, json.Unmarshal([]byte(`{"output":null}`), &empty))
+	assert.Zero(t, empty.Output)
+}
+
 func TestListDatapointsRequest(t *testing.T) {
 	funcName := "filter_func"
 	limit := 5
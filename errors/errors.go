@@ -1,24 +1,81 @@
 // Package errors provides structured error types for the TensorZero client.
 // This includes API errors, validation errors, and client-side errors.
+//
+// Errors returned by the gateway are parsed into a TensorZeroError with a
+// Category. The sentinel values can be used with errors.Is:
+//
+//	if errors.Is(err, tzerrors.ErrUnknownFunction) {
+//		// fix the configuration
+//	}
 package errors
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 )
 
+// Category classifies errors returned by the TensorZero gateway
+type Category string
+
+const (
+	CategoryUnknown           Category = "unknown"
+	CategoryInvalidRequest    Category = "invalid_request"
+	CategoryUnknownFunction   Category = "unknown_function"
+	CategoryUnknownVariant    Category = "unknown_variant"
+	CategoryAllVariantsFailed Category = "all_variants_failed"
+	CategoryRateLimited       Category = "rate_limited"
+	CategoryTimeout           Category = "timeout"
+)
+
+// Sentinel errors matching the categories of a TensorZeroError
+var (
+	ErrInvalidRequest    = errors.New("invalid request")
+	ErrUnknownFunction   = errors.New("unknown function")
+	ErrUnknownVariant    = errors.New("unknown variant")
+	ErrAllVariantsFailed = errors.New("all variants failed")
+	ErrRateLimited       = errors.New("provider rate limit exceeded")
+	ErrTimeout           = errors.New("timeout")
+)
+
+var categorySentinels = map[Category]error{
+	CategoryInvalidRequest:    ErrInvalidRequest,
+	CategoryUnknownFunction:   ErrUnknownFunction,
+	CategoryUnknownVariant:    ErrUnknownVariant,
+	CategoryAllVariantsFailed: ErrAllVariantsFailed,
+	CategoryRateLimited:       ErrRateLimited,
+	CategoryTimeout:           ErrTimeout,
+}
+
 // TensorZeroError represents a TensorZero API error
 type TensorZeroError struct {
-	Message    string `json:"message"`
-	StatusCode int    `json:"status_code,omitempty"`
-	RequestID  string `json:"request_id,omitempty"`
+	Message    string   `json:"message"`
+	StatusCode int      `json:"status_code,omitempty"`
+	RequestID  string   `json:"request_id,omitempty"`
+	Category   Category `json:"category,omitempty"`
+
+	// Text holds the raw response body returned by the gateway
+	Text string `json:"text,omitempty"`
 }
 
 func (e *TensorZeroError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = e.Text
+	}
 	if e.StatusCode > 0 {
-		return fmt.Sprintf("TensorZero API error (status %d): %s", e.StatusCode, e.Message)
+		return fmt.Sprintf("TensorZero API error (status %d): %s", e.StatusCode, message)
 	}
-	return fmt.Sprintf("TensorZero error: %s", e.Message)
+	return fmt.Sprintf("TensorZero error: %s", message)
+}
+
+// Is reports whether the error belongs to the category of the given sentinel
+func (e *TensorZeroError) Is(target error) bool {
+	sentinel, ok := categorySentinels[e.Category]
+	return ok && sentinel == target
 }
 
 // NewTensorZeroError creates a new TensorZero error
@@ -26,7 +83,145 @@ func NewTensorZeroError(message string, statusCode int) *TensorZeroError {
 	return &TensorZeroError{
 		Message:    message,
 		StatusCode: statusCode,
+		Category:   classify(statusCode, message, ""),
+	}
+}
+
+// errorBody is the JSON error body returned by the gateway. The error field is
+// either a plain message or an object with a message field; error_json carries
+// the structured error details keyed by the error kind.
+type errorBody struct {
+	Error     json.RawMessage            `json:"error"`
+	ErrorJSON map[string]json.RawMessage `json:"error_json"`
+	RequestID string                     `json:"request_id"`
+}
+
+// ParseAPIError creates a TensorZeroError from a failed gateway response
+func ParseAPIError(statusCode int, header http.Header, body []byte) *TensorZeroError {
+	e := &TensorZeroError{
+		StatusCode: statusCode,
+		Text:       string(body),
+	}
+	if header != nil {
+		e.RequestID = header.Get("X-Request-Id")
+	}
+
+	var kind string
+	var parsed errorBody
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		e.Message = parseErrorMessage(parsed.Error)
+		if e.RequestID == "" {
+			e.RequestID = parsed.RequestID
+		}
+		kind = errorKind(parsed.ErrorJSON)
 	}
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(statusCode)
+	}
+
+	e.Category = classify(statusCode, e.Message, kind)
+	return e
+}
+
+// parseErrorMessage extracts the message from the error field of a body
+func parseErrorMessage(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var message string
+	if err := json.Unmarshal(raw, &message); err == nil {
+		return message
+	}
+	var object struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &object); err == nil {
+		return object.Message
+	}
+	return string(raw)
+}
+
+// errorKind returns the error kind reported in error_json. Its keys are sorted
+// so that the kind does not depend on map order, and the first key naming a
+// known category wins.
+func errorKind(errorJSON map[string]json.RawMessage) string {
+	if len(errorJSON) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(errorJSON))
+	for key := range errorJSON {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if matchCategory(key, true) != CategoryUnknown {
+			return key
+		}
+	}
+	return keys[0]
+}
+
+// classify derives the error category from the error kind reported in
+// error_json, then from the status code. The message is only a fallback: it
+// names unknown functions and variants and failed variants for any status, but
+// rate limits and timeouts only if the status does not describe the failure.
+func classify(statusCode int, message, kind string) Category {
+	if category := matchCategory(kind, true); category != CategoryUnknown {
+		return category
+	}
+
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return CategoryRateLimited
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return CategoryTimeout
+	}
+
+	generic := statusCode == 0 || statusCode >= http.StatusInternalServerError
+	if category := matchCategory(message, generic); category != CategoryUnknown {
+		return category
+	}
+
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return CategoryInvalidRequest
+	}
+	return CategoryUnknown
+}
+
+// matchCategory derives the category named by an error kind or message.
+// Rate limits and timeouts are only matched if transient is set.
+func matchCategory(text string, transient bool) Category {
+	text = strings.ToLower(text)
+	switch {
+	case text == "":
+		return CategoryUnknown
+	case strings.Contains(text, "unknownfunction"), strings.Contains(text, "unknown function"):
+		return CategoryUnknownFunction
+	case strings.Contains(text, "unknownvariant"), strings.Contains(text, "unknown variant"):
+		return CategoryUnknownVariant
+	case strings.Contains(text, "allvariantsfailed"), strings.Contains(text, "all variants failed"):
+		return CategoryAllVariantsFailed
+	case !transient:
+		return CategoryUnknown
+	case strings.Contains(text, "ratelimit"), strings.Contains(text, "rate limit"):
+		return CategoryRateLimited
+	case strings.Contains(text, "timed out"), strings.Contains(text, "timeout"):
+		return CategoryTimeout
+	}
+	return CategoryUnknown
+}
+
+// TensorZeroInternalError represents an internal error
+type TensorZeroInternalError struct {
+	Message string
+}
+
+func (e *TensorZeroInternalError) Error() string {
+	return e.Message
 }
 
 // ValidationError represents a client-side validation error
@@ -49,8 +244,9 @@ func NewValidationError(field, message string) *ValidationError {
 
 // IsRetryable determines if an error is retryable
 func IsRetryable(err error) bool {
-	if tzErr, ok := err.(*TensorZeroError); ok {
+	var tzErr *TensorZeroError
+	if errors.As(err, &tzErr) {
 		return tzErr.StatusCode >= 500 || tzErr.StatusCode == http.StatusTooManyRequests
 	}
 	return false
-}
\ No newline at end of file
+}
@@ -3,6 +3,9 @@
 package errors
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -72,4 +75,139 @@ func TestIsRetryable(t *testing.T) {
 			assert.Equal(t, tt.expected, IsRetryable(tt.err))
 		})
 	}
-}
\ No newline at end of file
+}
+func TestParseAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		category   Category
+		sentinel   error
+		message    string
+	}{
+		{
+			name:       "plain error message",
+			statusCode: http.StatusBadRequest,
+			body:       `{"error": "Invalid request: missing field input"}`,
+			category:   CategoryInvalidRequest,
+			sentinel:   ErrInvalidRequest,
+			message:    "Invalid request: missing field input",
+		},
+		{
+			name:       "unknown function",
+			statusCode: http.StatusNotFound,
+			body:       `{"error": "Unknown function: my_function"}`,
+			category:   CategoryUnknownFunction,
+			sentinel:   ErrUnknownFunction,
+			message:    "Unknown function: my_function",
+		},
+		{
+			name:       "unknown variant from error_json",
+			statusCode: http.StatusNotFound,
+			body:       `{"error": "Variant not found", "error_json": {"UnknownVariant": {"name": "v1"}}}`,
+			category:   CategoryUnknownVariant,
+			sentinel:   ErrUnknownVariant,
+			message:    "Variant not found",
+		},
+		{
+			name:       "all variants failed",
+			statusCode: http.StatusBadGateway,
+			body:       `{"error": "All variants failed with errors: provider error"}`,
+			category:   CategoryAllVariantsFailed,
+			sentinel:   ErrAllVariantsFailed,
+			message:    "All variants failed with errors: provider error",
+		},
+		{
+			name:       "provider rate limit",
+			statusCode: http.StatusTooManyRequests,
+			body:       `{"error": {"message": "Too many requests"}}`,
+			category:   CategoryRateLimited,
+			sentinel:   ErrRateLimited,
+			message:    "Too many requests",
+		},
+		{
+			name:       "timeout",
+			statusCode: http.StatusRequestTimeout,
+			body:       `{"error": "Inference timed out"}`,
+			category:   CategoryTimeout,
+			sentinel:   ErrTimeout,
+			message:    "Inference timed out",
+		},
+		{
+			name:       "timeout in the message of a client error",
+			statusCode: http.StatusBadRequest,
+			body:       `{"error": "Invalid timeout_ms: must be positive"}`,
+			category:   CategoryInvalidRequest,
+			sentinel:   ErrInvalidRequest,
+			message:    "Invalid timeout_ms: must be positive",
+		},
+		{
+			name:       "timeout in the message of a server error",
+			statusCode: http.StatusBadGateway,
+			body:       `{"error": "Model provider timed out"}`,
+			category:   CategoryTimeout,
+			sentinel:   ErrTimeout,
+			message:    "Model provider timed out",
+		},
+		{
+			name:       "kind from several error_json keys",
+			statusCode: http.StatusNotFound,
+			body:       `{"error": "Function not found", "error_json": {"Details": {}, "UnknownFunction": {"name": "f"}, "Other": {}}}`,
+			category:   CategoryUnknownFunction,
+			sentinel:   ErrUnknownFunction,
+			message:    "Function not found",
+		},
+		{
+			name:       "non JSON body",
+			statusCode: http.StatusInternalServerError,
+			body:       "upstream exploded",
+			category:   CategoryUnknown,
+			message:    "upstream exploded",
+		},
+		{
+			name:       "empty body",
+			statusCode: http.StatusServiceUnavailable,
+			body:       "",
+			category:   CategoryUnknown,
+			message:    "Service Unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParseAPIError(tt.statusCode, nil, []byte(tt.body))
+			assert.Equal(t, tt.statusCode, err.StatusCode)
+			assert.Equal(t, tt.category, err.Category)
+			assert.Equal(t, tt.message, err.Message)
+			assert.Equal(t, tt.body, err.Text)
+			if tt.sentinel != nil {
+				assert.True(t, errors.Is(fmt.Errorf("wrapped: %w", err), tt.sentinel))
+			}
+		})
+	}
+}
+
+func TestErrorKindIsDeterministic(t *testing.T) {
+	errorJSON := map[string]json.RawMessage{"Zeta": nil, "Alpha": nil, "Beta": nil}
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "Alpha", errorKind(errorJSON))
+	}
+	errorJSON["UnknownVariant"] = nil
+	assert.Equal(t, "UnknownVariant", errorKind(errorJSON))
+	assert.Empty(t, errorKind(nil))
+}
+
+func TestParseAPIErrorRequestID(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Request-Id", "req-header")
+	err := ParseAPIError(http.StatusBadRequest, header, []byte(`{"error": "bad", "request_id": "req-body"}`))
+	assert.Equal(t, "req-header", err.RequestID)
+
+	err = ParseAPIError(http.StatusBadRequest, nil, []byte(`{"error": "bad", "request_id": "req-body"}`))
+	assert.Equal(t, "req-body", err.RequestID)
+}
+
+func TestIsRetryableWrapped(t *testing.T) {
+	err := fmt.Errorf("call failed: %w", ParseAPIError(http.StatusBadGateway, nil, nil))
+	assert.True(t, IsRetryable(err))
+}
@@ -0,0 +1,194 @@
+package tensorzero
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/tool"
+)
+
+// ErrNotChatStream is returned by Stream.Events for streams of JSON functions
+var ErrNotChatStream = errors.New("stream events require a chat stream")
+
+// StreamEvent is a typed event of a chat stream, see Stream.Events. It is one of
+// TextDelta, ThoughtDelta, ToolCallStarted, ToolCallArgumentsDelta,
+// ToolCallCompleted, UsageReported or Finished.
+type StreamEvent interface {
+	streamEvent()
+}
+
+// TextDelta carries the next fragment of a text block
+type TextDelta struct {
+	ID   string
+	Text string
+}
+
+// ThoughtDelta carries the next fragment of a thought block
+type ThoughtDelta struct {
+	ID        string
+	Text      string
+	Signature *string
+}
+
+// ToolCallStarted is sent when a tool call with a new ID appears
+type ToolCallStarted struct {
+	ID   string
+	Name string
+}
+
+// ToolCallArgumentsDelta carries the next fragment of the raw arguments of a tool call
+type ToolCallArgumentsDelta struct {
+	ID    string
+	Delta string
+}
+
+// ToolCallCompleted is sent for every tool call when the model finished generating.
+// Arguments holds the parsed arguments; if the raw arguments are not a JSON
+// object, Arguments is nil and Err describes the problem.
+type ToolCallCompleted struct {
+	ID           string
+	Name         string
+	RawArguments string
+	Arguments    map[string]interface{}
+	Err          error
+}
+
+// UsageReported carries the token usage reported by the gateway
+type UsageReported struct {
+	Usage shared.Usage
+}
+
+// Finished is sent when the gateway reports why the model stopped
+type Finished struct {
+	Reason inference.FinishReason
+}
+
+func (TextDelta) streamEvent()              {}
+func (ThoughtDelta) streamEvent()           {}
+func (ToolCallStarted) streamEvent()        {}
+func (ToolCallArgumentsDelta) streamEvent() {}
+func (ToolCallCompleted) streamEvent()      {}
+func (UsageReported) streamEvent()          {}
+func (Finished) streamEvent()               {}
+
+// EventDecoder turns the chunks of a chat stream into stream events. It keeps
+// track of tool calls by ID so that their start and completion are reported
+// once.
+type EventDecoder struct {
+	calls     []*pendingToolCall
+	callsByID map[string]*pendingToolCall
+}
+
+// pendingToolCall collects the fragments of a tool call until it completes
+type pendingToolCall struct {
+	id        string
+	name      string
+	arguments []byte
+}
+
+// Decode returns the events of a chunk. Tool calls are completed before the
+// Finished event of the chunk that carries the finish reason.
+func (d *EventDecoder) Decode(chunk *inference.ChatChunk) ([]StreamEvent, error) {
+	events := make([]StreamEvent, 0, len(chunk.Content)+1)
+	for _, block := range chunk.Content {
+		switch c := block.(type) {
+		case *shared.TextChunk:
+			events = append(events, TextDelta{ID: c.ID, Text: c.Text})
+		case *shared.ThoughtChunk:
+			events = append(events, ThoughtDelta{ID: c.ID, Text: c.Text, Signature: c.Signature})
+		case *tool.ToolCallChunk:
+			events = d.toolCall(events, c)
+		default:
+			return nil, fmt.Errorf("unsupported content block chunk type %T", block)
+		}
+	}
+
+	if chunk.Usage != nil {
+		events = append(events, UsageReported{Usage: *chunk.Usage})
+	}
+	if chunk.FinishReason != nil {
+		events = append(events, d.Flush()...)
+		events = append(events, Finished{Reason: *chunk.FinishReason})
+	}
+	return events, nil
+}
+
+// Flush completes the tool calls that are still open, for example when a
+// stream ended without a finish reason
+func (d *EventDecoder) Flush() []StreamEvent {
+	events := make([]StreamEvent, 0, len(d.calls))
+	for _, call := range d.calls {
+		completed := ToolCallCompleted{ID: call.id, Name: call.name, RawArguments: string(call.arguments)}
+		if err := json.Unmarshal(call.arguments, &completed.Arguments); err != nil {
+			completed.Err = fmt.Errorf("invalid arguments for tool call %s: %w", call.id, err)
+		} else if completed.Arguments == nil {
+			completed.Err = fmt.Errorf("invalid arguments for tool call %s: not a JSON object", call.id)
+		}
+		events = append(events, completed)
+	}
+	d.calls = nil
+	d.callsByID = nil
+	return events
+}
+
+// toolCall appends the events of a tool call chunk
+func (d *EventDecoder) toolCall(events []StreamEvent, c *tool.ToolCallChunk) []StreamEvent {
+	call, ok := d.callsByID[c.ID]
+	if !ok {
+		if d.callsByID == nil {
+			d.callsByID = make(map[string]*pendingToolCall)
+		}
+		call = &pendingToolCall{id: c.ID}
+		d.callsByID[c.ID] = call
+		d.calls = append(d.calls, call)
+		events = append(events, ToolCallStarted{ID: c.ID, Name: c.RawName})
+	}
+	call.name += c.RawName
+	if c.RawArguments != "" {
+		call.arguments = append(call.arguments, c.RawArguments...)
+		events = append(events, ToolCallArgumentsDelta{ID: c.ID, Delta: c.RawArguments})
+	}
+	return events
+}
+
+// Events returns an iterator over the typed events of a chat stream. Open tool
+// calls are completed at the end of the stream if the gateway sent no finish
+// reason. Events only supports streams of chat functions: on a stream of a JSON
+// function, the first chunk ends the iteration with an error matching
+// ErrNotChatStream; use Accumulate or inference.PartialJSON instead. The stream is
+// closed when the iteration ends.
+func (s *Stream) Events() iter.Seq2[StreamEvent, error] {
+	return func(yield func(StreamEvent, error) bool) {
+		var decoder EventDecoder
+		for chunk, err := range s.All() {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			chat, ok := chunk.(*inference.ChatChunk)
+			if !ok {
+				yield(nil, fmt.Errorf("%w, got %T", ErrNotChatStream, chunk))
+				return
+			}
+			events, err := decoder.Decode(chat)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, event := range events {
+				if !yield(event, nil) {
+					return
+				}
+			}
+		}
+		for _, event := range decoder.Flush() {
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
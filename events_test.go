@@ -0,0 +1,133 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/tool"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chatEvent(content, extra string) string {
+	return `data: {"inference_id":"550e8400-e29b-41d4-a716-446655440000","episode_id":"550e8400-e29b-41d4-a716-446655440001","variant_name":"v1","content":[` + content + `]` + extra + "}\n\n"
+}
+
+func TestStreamEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(chatEvent(`{"type":"thought","id":"0","text":"Hmm"}`, "")))
+		w.Write([]byte(chatEvent(`{"type":"text","id":"1","text":"Checking"}`, "")))
+		w.Write([]byte(chatEvent(`{"type":"tool_call","id":"call_1","raw_name":"get_weather","raw_arguments":"{\"city\":"}`, "")))
+		w.Write([]byte(chatEvent(`{"type":"tool_call","id":"call_1","raw_name":"","raw_arguments":"\"Berlin\"}"}`, "")))
+		w.Write([]byte(chatEvent(``, `,"usage":{"input_tokens":10,"output_tokens":5},"finish_reason":"tool_call"`)))
+	}))
+	defer server.Close()
+
+	stream, err := OpenStream(context.Background(), NewHTTPGateway(server.URL), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+	require.NoError(t, err)
+
+	var events []StreamEvent
+	for event, err := range stream.Events() {
+		require.NoError(t, err)
+		events = append(events, event)
+	}
+
+	assert.Equal(t, []StreamEvent{
+		ThoughtDelta{ID: "0", Text: "Hmm"},
+		TextDelta{ID: "1", Text: "Checking"},
+		ToolCallStarted{ID: "call_1", Name: "get_weather"},
+		ToolCallArgumentsDelta{ID: "call_1", Delta: `{"city":`},
+		ToolCallArgumentsDelta{ID: "call_1", Delta: `"Berlin"}`},
+		UsageReported{Usage: shared.Usage{InputTokens: 10, OutputTokens: 5}},
+		ToolCallCompleted{ID: "call_1", Name: "get_weather", RawArguments: `{"city":"Berlin"}`, Arguments: map[string]interface{}{"city": "Berlin"}},
+		Finished{Reason: inference.FinishReasonToolCall},
+	}, events)
+}
+
+func TestStreamEventsFlushAtEnd(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(chatEvent(`{"type":"tool_call","id":"call_1","raw_name":"search","raw_arguments":"{\"q\": "}`, "")))
+	}))
+	defer server.Close()
+
+	stream, err := OpenStream(context.Background(), NewHTTPGateway(server.URL), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+	require.NoError(t, err)
+
+	var last StreamEvent
+	for event, err := range stream.Events() {
+		require.NoError(t, err)
+		last = event
+	}
+
+	completed, ok := last.(ToolCallCompleted)
+	require.True(t, ok)
+	assert.Equal(t, `{"q": `, completed.RawArguments)
+	assert.Nil(t, completed.Arguments)
+	assert.Error(t, completed.Err)
+}
+
+func TestStreamEventsJsonStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(streamEvent))
+	}))
+	defer server.Close()
+
+	stream, err := OpenStream(context.Background(), NewHTTPGateway(server.URL), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	require.NoError(t, err)
+
+	for _, err := range stream.Events() {
+		require.ErrorIs(t, err, ErrNotChatStream)
+	}
+}
+
+func TestStreamEventsError(t *testing.T) {
+	chunkCh := make(chan inference.InferenceChunk)
+	errCh := make(chan error, 1)
+	close(chunkCh)
+	errCh <- errors.New("broken")
+	close(errCh)
+
+	var got error
+	for _, err := range StreamFromChannels(chunkCh, errCh, func() {}).Events() {
+		got = err
+	}
+	assert.EqualError(t, got, "broken")
+}
+
+func TestEventDecoderParallelToolCalls(t *testing.T) {
+	var decoder EventDecoder
+	events, err := decoder.Decode(&inference.ChatChunk{Content: []inference.ContentBlockChunk{
+		&tool.ToolCallChunk{ID: "a", RawName: "first", RawArguments: "{}"},
+		&tool.ToolCallChunk{ID: "b", RawName: "second", RawArguments: "[1]"},
+	}})
+	require.NoError(t, err)
+	assert.Len(t, events, 4)
+
+	stop := inference.FinishReasonToolCall
+	events, err = decoder.Decode(&inference.ChatChunk{FinishReason: &stop})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	first := events[0].(ToolCallCompleted)
+	assert.Equal(t, "a", first.ID)
+	assert.NoError(t, first.Err)
+	assert.Equal(t, map[string]interface{}{}, first.Arguments)
+
+	second := events[1].(ToolCallCompleted)
+	assert.Equal(t, "b", second.ID)
+	assert.Error(t, second.Err)
+
+	assert.Equal(t, Finished{Reason: stop}, events[2])
+	assert.Empty(t, decoder.Flush())
+}
@@ -0,0 +1,223 @@
+package tensorzero
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidOutput is returned by typed function calls whose output cannot be
+// decoded into the output type, including JSON outputs the gateway could not parse
+var ErrInvalidOutput = errors.New("invalid function output")
+
+// Meta describes the inference behind a typed function call
+type Meta struct {
+	InferenceID  uuid.UUID
+	EpisodeID    uuid.UUID
+	VariantName  string
+	Usage        shared.Usage
+	FinishReason *inference.FinishReason
+
+	// Response is the response returned by the gateway
+	Response inference.InferenceResponse
+}
+
+func newMeta(resp inference.InferenceResponse) Meta {
+	return Meta{
+		InferenceID:  resp.GetInferenceID(),
+		EpisodeID:    resp.GetEpisodeID(),
+		VariantName:  resp.GetVariantName(),
+		Usage:        resp.GetUsage(),
+		FinishReason: resp.GetFinishReason(),
+		Response:     resp,
+	}
+}
+
+// FunctionOption configures a typed function handle
+type FunctionOption func(*functionConfig)
+
+type functionConfig struct {
+	requestOptions []inference.InferenceRequestOption
+	deriveSchema   bool
+}
+
+// WithRequestOptions applies the given options to every request of the function,
+// e.g. to set tags, a variant or inference parameters
+func WithRequestOptions(options ...inference.InferenceRequestOption) FunctionOption {
+	return func(c *functionConfig) {
+		c.requestOptions = append(c.requestOptions, options...)
+	}
+}
+
+// WithDerivedOutputSchema sends the output schema derived from the output type
+// with OutputSchemaFor, instead of the schema of the function configuration
+func WithDerivedOutputSchema() FunctionOption {
+	return func(c *functionConfig) {
+		c.deriveSchema = true
+	}
+}
+
+// function holds what JSON and chat function handles have in common
+type function[In any] struct {
+	gateway Gateway
+	name    string
+	config  functionConfig
+}
+
+func newFunction[In any](gateway Gateway, name string, options []FunctionOption) function[In] {
+	f := function[In]{gateway: gateway, name: name}
+	for _, option := range options {
+		option(&f.config)
+	}
+	return f
+}
+
+// infer sends the input as a user message after the messages set by the options
+func (f *function[In]) infer(ctx context.Context, in In, outputSchema map[string]interface{}, options []inference.InferenceRequestOption) (inference.InferenceResponse, error) {
+	block, err := templateContent(in)
+	if err != nil {
+		return nil, fmt.Errorf("function %s: %w", f.name, err)
+	}
+
+	req := &inference.InferenceRequest{FunctionName: &f.name}
+	for _, option := range f.config.requestOptions {
+		option(req)
+	}
+	for _, option := range options {
+		option(req)
+	}
+	if outputSchema != nil && req.OutputSchema == nil {
+		req.OutputSchema = outputSchema
+	}
+	req.Input.Messages = append(req.Input.Messages, shared.Message{
+		Role:    "user",
+		Content: []shared.ContentBlock{block},
+	})
+
+	return f.gateway.Inference(ctx, req)
+}
+
+// templateContent encodes the input as the arguments of the user template, or
+// as plain text if it encodes as a JSON string
+func templateContent(in interface{}) (shared.ContentBlock, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode input: %w", err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to encode input: %w", err)
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return shared.NewTextWithArguments(value), nil
+	case string:
+		return shared.NewText(value), nil
+	}
+	return nil, fmt.Errorf("input must encode as a JSON object or string, got %s", data)
+}
+
+// JSONFunctionHandle calls a JSON function with typed input and output
+type JSONFunctionHandle[In, Out any] struct {
+	function[In]
+	schema    map[string]interface{}
+	schemaErr error
+}
+
+// JSONFunction returns a handle for the JSON function with the given name:
+//
+//	extract := tensorzero.JSONFunction[Document, Entities](client, "extract_entities")
+//	entities, meta, err := extract.Call(ctx, Document{Text: text})
+//
+// The input is sent as the arguments of the user template and the parsed
+// output is decoded into Out.
+func JSONFunction[In, Out any](gateway Gateway, name string, options ...FunctionOption) *JSONFunctionHandle[In, Out] {
+	h := &JSONFunctionHandle[In, Out]{function: newFunction[In](gateway, name, options)}
+	if h.config.deriveSchema {
+		h.schema, h.schemaErr = OutputSchemaFor[Out]()
+	}
+	return h
+}
+
+// Call runs an inference of the function. The options apply to this call only.
+// The returned Meta is set whenever the gateway responded, also if the output
+// could not be decoded.
+func (h *JSONFunctionHandle[In, Out]) Call(ctx context.Context, in In, options ...inference.InferenceRequestOption) (Out, Meta, error) {
+	var out Out
+	if h.schemaErr != nil {
+		return out, Meta{}, fmt.Errorf("function %s: %w", h.name, h.schemaErr)
+	}
+
+	resp, err := h.infer(ctx, in, h.schema, options)
+	if err != nil {
+		return out, Meta{}, err
+	}
+	meta := newMeta(resp)
+
+	jsonResp, ok := resp.(*inference.JsonInferenceResponse)
+	if !ok {
+		return out, meta, fmt.Errorf("function %s: %w: expected a JSON response, got %T", h.name, ErrInvalidOutput, resp)
+	}
+	if jsonResp.Output.Parsed == nil {
+		raw := ""
+		if jsonResp.Output.Raw != nil {
+			raw = *jsonResp.Output.Raw
+		}
+		return out, meta, fmt.Errorf("function %s: %w: output was not parsed: %s", h.name, ErrInvalidOutput, raw)
+	}
+
+	data, err := json.Marshal(jsonResp.Output.Parsed)
+	if err != nil {
+		return out, meta, fmt.Errorf("function %s: %w: %w", h.name, ErrInvalidOutput, err)
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, meta, fmt.Errorf("function %s: %w: %w", h.name, ErrInvalidOutput, err)
+	}
+	return out, meta, nil
+}
+
+// ChatFunctionHandle calls a chat function with typed input and returns its text
+type ChatFunctionHandle[In any] struct {
+	function[In]
+}
+
+// ChatFunction returns a handle for the chat function with the given name:
+//
+//	summarize := tensorzero.ChatFunction[Article](client, "summarize")
+//	summary, meta, err := summarize.Call(ctx, Article{Body: body})
+//
+// The input is sent as the arguments of the user template, or as text if In
+// is a string.
+func ChatFunction[In any](gateway Gateway, name string, options ...FunctionOption) *ChatFunctionHandle[In] {
+	return &ChatFunctionHandle[In]{function: newFunction[In](gateway, name, options)}
+}
+
+// Call runs an inference of the function and returns the concatenated text of
+// the response. The options apply to this call only.
+func (h *ChatFunctionHandle[In]) Call(ctx context.Context, in In, options ...inference.InferenceRequestOption) (string, Meta, error) {
+	resp, err := h.infer(ctx, in, nil, options)
+	if err != nil {
+		return "", Meta{}, err
+	}
+	meta := newMeta(resp)
+
+	chatResp, ok := resp.(*inference.ChatInferenceResponse)
+	if !ok {
+		return "", meta, fmt.Errorf("function %s: %w: expected a chat response, got %T", h.name, ErrInvalidOutput, resp)
+	}
+
+	var text strings.Builder
+	for _, block := range chatResp.Content {
+		if t, ok := block.(*shared.Text); ok && t.Text != nil {
+			text.WriteString(*t.Text)
+		}
+	}
+	return text.String(), meta, nil
+}
@@ -0,0 +1,160 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type extractInput struct {
+	Text     string `json:"text"`
+	Language string `json:"language,omitempty"`
+}
+
+type extractOutput struct {
+	People []string `json:"people"`
+	Places []string `json:"places"`
+}
+
+const testInferenceID = "550e8400-e29b-41d4-a716-446655440000"
+
+func jsonGateway(output inference.JsonInferenceOutput, check func(req *inference.InferenceRequest)) *MockGateway {
+	return &MockGateway{
+		InferenceFn: func(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error) {
+			if check != nil {
+				check(req)
+			}
+			return &inference.JsonInferenceResponse{
+				InferenceID: uuid.MustParse(testInferenceID),
+				VariantName: "v1",
+				Output:      output,
+				Usage:       shared.Usage{InputTokens: 12, OutputTokens: 7},
+			}, nil
+		},
+	}
+}
+
+func TestJSONFunctionCall(t *testing.T) {
+	gateway := jsonGateway(inference.JsonInferenceOutput{
+		Raw:    util.StringPtr(`{"people":["Ada"],"places":["London"]}`),
+		Parsed: map[string]interface{}{"people": []interface{}{"Ada"}, "places": []interface{}{"London"}},
+	}, func(req *inference.InferenceRequest) {
+		assert.Equal(t, "extract_entities", *req.FunctionName)
+		assert.Equal(t, "test", req.Tags["source"])
+		assert.Equal(t, 0.1, *req.Params.ChatCompletion.Temperature)
+		assert.Nil(t, req.OutputSchema)
+
+		require.Len(t, req.Input.Messages, 2)
+		assert.Equal(t, "system", req.Input.Messages[0].Role)
+		message := req.Input.Messages[1]
+		assert.Equal(t, "user", message.Role)
+		assert.Equal(t, []shared.ContentBlock{shared.NewTextWithArguments(map[string]interface{}{"text": "Ada lived in London"})}, message.Content)
+	})
+
+	extract := JSONFunction[extractInput, extractOutput](gateway, "extract_entities",
+		WithRequestOptions(inference.WithTags(map[string]string{"source": "test"})))
+	out, meta, err := extract.Call(context.Background(), extractInput{Text: "Ada lived in London"},
+		inference.WithTemperature(0.1), inference.WithSystemMessage("Extract entities"))
+	require.NoError(t, err)
+
+	assert.Equal(t, extractOutput{People: []string{"Ada"}, Places: []string{"London"}}, out)
+	assert.Equal(t, uuid.MustParse(testInferenceID), meta.InferenceID)
+	assert.Equal(t, "v1", meta.VariantName)
+	assert.Equal(t, 7, meta.Usage.OutputTokens)
+	assert.IsType(t, &inference.JsonInferenceResponse{}, meta.Response)
+}
+
+func TestJSONFunctionDerivedOutputSchema(t *testing.T) {
+	var schema map[string]interface{}
+	gateway := jsonGateway(inference.JsonInferenceOutput{Parsed: map[string]interface{}{}}, func(req *inference.InferenceRequest) {
+		schema = req.OutputSchema
+	})
+
+	extract := JSONFunction[extractInput, extractOutput](gateway, "extract_entities", WithDerivedOutputSchema())
+	_, _, err := extract.Call(context.Background(), extractInput{Text: "x"})
+	require.NoError(t, err)
+
+	want, err := OutputSchemaFor[extractOutput]()
+	require.NoError(t, err)
+	assert.Equal(t, want, schema)
+
+	invalid := JSONFunction[extractInput, []string](gateway, "extract_entities", WithDerivedOutputSchema())
+	_, _, err = invalid.Call(context.Background(), extractInput{Text: "x"})
+	assert.ErrorContains(t, err, "does not encode as a JSON object")
+}
+
+func TestJSONFunctionInvalidOutput(t *testing.T) {
+	gateway := jsonGateway(inference.JsonInferenceOutput{Raw: util.StringPtr(`{"people":`)}, nil)
+	extract := JSONFunction[extractInput, extractOutput](gateway, "extract_entities")
+
+	_, meta, err := extract.Call(context.Background(), extractInput{Text: "x"})
+	assert.ErrorIs(t, err, ErrInvalidOutput)
+	assert.ErrorContains(t, err, `{"people":`)
+	assert.Equal(t, uuid.MustParse(testInferenceID), meta.InferenceID)
+
+	gateway = jsonGateway(inference.JsonInferenceOutput{Parsed: map[string]interface{}{"people": "Ada"}}, nil)
+	extract = JSONFunction[extractInput, extractOutput](gateway, "extract_entities")
+	_, _, err = extract.Call(context.Background(), extractInput{Text: "x"})
+	assert.ErrorIs(t, err, ErrInvalidOutput)
+
+	summarize := ChatFunction[string](gateway, "summarize")
+	_, _, err = summarize.Call(context.Background(), "x")
+	assert.ErrorIs(t, err, ErrInvalidOutput)
+}
+
+func TestJSONFunctionInputErrors(t *testing.T) {
+	called := false
+	gateway := jsonGateway(inference.JsonInferenceOutput{}, func(*inference.InferenceRequest) { called = true })
+
+	_, _, err := JSONFunction[[]string, extractOutput](gateway, "f").Call(context.Background(), []string{"a"})
+	assert.ErrorContains(t, err, "input must encode as a JSON object or string")
+
+	_, _, err = JSONFunction[chan int, extractOutput](gateway, "f").Call(context.Background(), make(chan int))
+	assert.ErrorContains(t, err, "failed to encode input")
+	assert.False(t, called)
+}
+
+func TestChatFunctionCall(t *testing.T) {
+	gateway := &MockGateway{
+		InferenceFn: func(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error) {
+			assert.Equal(t, []shared.ContentBlock{shared.NewText("Long text")}, req.Input.Messages[0].Content)
+			stop := inference.FinishReasonStop
+			return &inference.ChatInferenceResponse{
+				InferenceID: uuid.MustParse(testInferenceID),
+				Content: []shared.ContentBlock{
+					&shared.Thought{Text: util.StringPtr("thinking"), Type: "thought"},
+					shared.NewText("Short "),
+					shared.NewText("text"),
+				},
+				FinishReason: &stop,
+			}, nil
+		},
+	}
+
+	summarize := ChatFunction[string](gateway, "summarize")
+	text, meta, err := summarize.Call(context.Background(), "Long text")
+	require.NoError(t, err)
+	assert.Equal(t, "Short text", text)
+	assert.Equal(t, inference.FinishReasonStop, *meta.FinishReason)
+}
+
+func TestFunctionGatewayError(t *testing.T) {
+	gateway := &MockGateway{
+		InferenceFn: func(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error) {
+			return nil, errors.New("unavailable")
+		},
+	}
+
+	_, meta, err := ChatFunction[extractInput](gateway, "f").Call(context.Background(), extractInput{Text: "x"})
+	assert.EqualError(t, err, "unavailable")
+	assert.Nil(t, meta.Response)
+}
@@ -0,0 +1,226 @@
+package tensorzero
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+)
+
+// HealthResponse is returned by the gateway's /health endpoint
+type HealthResponse struct {
+	// Gateway is "ok" when the gateway is serving requests.
+	Gateway string `json:"gateway"`
+
+	// ClickHouse is "ok" when the gateway can reach ClickHouse.
+	ClickHouse string `json:"clickhouse,omitempty"`
+}
+
+// StatusResponse is returned by the gateway's /status endpoint
+type StatusResponse struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
+}
+
+// readyPollInterval is the delay between two health checks in WaitUntilReady
+const readyPollInterval = 500 * time.Millisecond
+
+// GatewayVersion is a TensorZero gateway version such as 2025.5.7
+type GatewayVersion struct {
+	Year, Month, Patch int
+}
+
+// ParseGatewayVersion parses a calendar version of the form YEAR.MONTH.PATCH
+func ParseGatewayVersion(s string) (GatewayVersion, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) != 3 {
+		return GatewayVersion{}, fmt.Errorf("invalid gateway version %q", s)
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return GatewayVersion{}, fmt.Errorf("invalid gateway version %q", s)
+		}
+		numbers[i] = n
+	}
+	return GatewayVersion{Year: numbers[0], Month: numbers[1], Patch: numbers[2]}, nil
+}
+
+func (v GatewayVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Year, v.Month, v.Patch)
+}
+
+// IsZero reports whether the version is unknown
+func (v GatewayVersion) IsZero() bool {
+	return v == GatewayVersion{}
+}
+
+// AtLeast reports whether v is the same as or newer than other
+func (v GatewayVersion) AtLeast(other GatewayVersion) bool {
+	if v.Year != other.Year {
+		return v.Year > other.Year
+	}
+	if v.Month != other.Month {
+		return v.Month > other.Month
+	}
+	return v.Patch >= other.Patch
+}
+
+// endpointRoute is a path template of an endpoint that moved between gateway
+// releases, and the first release serving it
+type endpointRoute struct {
+	path  string
+	since GatewayVersion
+}
+
+// endpointRoutes lists, newest first, the routes of endpoints that moved
+// between gateway releases. The version reported by Status selects the route
+// tried first; routes the gateway does not serve are detected from its
+// responses, see DynamicEvaluationRunEpisode, and the served route is remembered.
+var endpointRoutes = map[Operation][]endpointRoute{
+	// Newer gateways take the run ID from the path instead of the body
+	OperationDynamicEvaluationRunEpisode: {
+		{path: "/dynamic_evaluation_run/{run_id}/episode", since: GatewayVersion{Year: 2025, Month: 5, Patch: 0}},
+		{path: "/dynamic_evaluation_run_episode"},
+	},
+}
+
+// endpoints returns the path templates to try for an operation, starting with
+// the route the gateway served before or else the route of its version
+func (g *httpGateway) endpoints(op Operation) []string {
+	routes := endpointRoutes[op]
+	if len(routes) == 0 {
+		return []string{operationEndpoints[op]}
+	}
+
+	var first string
+	if detected, ok := g.routes.Load(op); ok {
+		first = detected.(string)
+	} else if version := g.version.Load(); version != nil {
+		for _, route := range routes {
+			if version.AtLeast(route.since) {
+				first = route.path
+				break
+			}
+		}
+	}
+
+	ordered := make([]string, 0, len(routes))
+	if first != "" {
+		ordered = append(ordered, first)
+	}
+	for _, route := range routes {
+		if route.path != first {
+			ordered = append(ordered, route.path)
+		}
+	}
+	return ordered
+}
+
+// endpoint returns the path template of an operation: the route the gateway
+// served before, the route of its version, or the newest one
+func (g *httpGateway) endpoint(op Operation) string {
+	return g.endpoints(op)[0]
+}
+
+// isMissingRoute reports whether the gateway answered 404 without a TensorZero
+// error body, which means that it does not serve the route at all
+func isMissingRoute(err error) bool {
+	var tzErr *tzerrors.TensorZeroError
+	if !errors.As(err, &tzErr) || tzErr.StatusCode != http.StatusNotFound {
+		return false
+	}
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	return json.Unmarshal([]byte(tzErr.Text), &body) != nil || body.Error == nil
+}
+
+// expandPath fills the {name} placeholders of a path template with the
+// escaped values given as name, value pairs
+func expandPath(template string, params ...string) string {
+	for i := 0; i+1 < len(params); i += 2 {
+		template = strings.ReplaceAll(template, "{"+params[i]+"}", url.PathEscape(params[i+1]))
+	}
+	return template
+}
+
+// Health checks whether the gateway is serving requests
+func (g *httpGateway) Health(ctx context.Context) (*HealthResponse, error) {
+	r, err := newAPIRequest(OperationHealth, "GET", "/health", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var healthResp HealthResponse
+	if err := g.doJSON(ctx, r, &healthResp); err != nil {
+		return nil, err
+	}
+
+	return &healthResp, nil
+}
+
+// Status returns the gateway status and version. The version is remembered to
+// choose the routes of endpoints that moved between gateway releases.
+func (g *httpGateway) Status(ctx context.Context) (*StatusResponse, error) {
+	r, err := newAPIRequest(OperationStatus, "GET", "/status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var statusResp StatusResponse
+	if err := g.doJSON(ctx, r, &statusResp); err != nil {
+		return nil, err
+	}
+
+	if version, err := ParseGatewayVersion(statusResp.Version); err == nil {
+		g.version.Store(&version)
+	}
+	return &statusResp, nil
+}
+
+// WaitUntilReady polls the health endpoint until the gateway is ready or ctx is done
+func (g *httpGateway) WaitUntilReady(ctx context.Context) (*StatusResponse, error) {
+	return waitUntilReady(ctx, g)
+}
+
+// healthChecker returns gw as a HealthChecker, or an error matching
+// errors.ErrUnsupported if it does not report the gateway health
+func healthChecker(gw Gateway) (HealthChecker, error) {
+	checker, ok := gw.(HealthChecker)
+	if !ok {
+		return nil, fmt.Errorf("%T does not report the gateway health: %w", gw, errors.ErrUnsupported)
+	}
+	return checker, nil
+}
+
+// waitUntilReady polls gw.Health until it succeeds and then returns gw.Status
+func waitUntilReady(ctx context.Context, gw HealthChecker) (*StatusResponse, error) {
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		health, err := gw.Health(ctx)
+		if err == nil && health.Gateway == "ok" {
+			return gw.Status(ctx)
+		}
+		if err == nil {
+			err = fmt.Errorf("gateway reported %q", health.Gateway)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gateway not ready: %w (last error: %v)", ctx.Err(), err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/evaluation"
+	"github.com/denkhaus/tensorzero/metrics"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStatusServer(t *testing.T, version string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"gateway": "ok", "clickhouse": "ok"}`))
+		case "/status":
+			w.Write([]byte(`{"status": "ok", "version": "` + version + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHealthAndStatus(t *testing.T) {
+	server := newStatusServer(t, "2025.5.7")
+	client := NewHTTPGateway(server.URL).(HealthChecker)
+
+	health, err := client.Health(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ok", health.Gateway)
+	assert.Equal(t, "ok", health.ClickHouse)
+
+	status, err := client.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ok", status.Status)
+	assert.Equal(t, "2025.5.7", status.Version)
+}
+
+func TestHealthUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error": "ClickHouse is not available"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPGateway(server.URL).(HealthChecker).Health(context.Background())
+	var tzErr *tzerrors.TensorZeroError
+	require.ErrorAs(t, err, &tzErr)
+	assert.Equal(t, http.StatusServiceUnavailable, tzErr.StatusCode)
+}
+
+func TestWaitUntilReady(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/status" {
+			w.Write([]byte(`{"status": "ok", "version": "2025.6.0"}`))
+			return
+		}
+		if atomic.AddInt32(&calls, 1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"gateway": "ok"}`))
+	}))
+	defer server.Close()
+
+	status, err := NewHTTPGateway(server.URL).(HealthChecker).WaitUntilReady(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "2025.6.0", status.Version)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestWaitUntilReadyTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := NewHTTPGateway(server.URL).(HealthChecker).WaitUntilReady(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "503")
+}
+
+func TestEpisodeRouteDetection(t *testing.T) {
+	runID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	newRoute := "/dynamic_evaluation_run/" + runID.String() + "/episode"
+	oldRoute := "/dynamic_evaluation_run_episode"
+	tests := []struct {
+		name    string
+		served  string
+		paths   []string
+		bodyRun bool
+	}{
+		{name: "new gateway", served: newRoute, paths: []string{newRoute, newRoute}},
+		{name: "old gateway", served: oldRoute, paths: []string{newRoute, oldRoute, oldRoute}, bodyRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			var bodyRun bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				if r.URL.Path != tt.served {
+					// Unknown routes have no TensorZero error body
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				var body map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&body)
+				_, bodyRun = body["run_id"]
+				w.Write([]byte(`{"episode_id": "550e8400-e29b-41d4-a716-446655440001"}`))
+			}))
+			defer server.Close()
+			client := NewHTTPGateway(server.URL)
+
+			// The detected route is remembered for later calls
+			for i := 0; i < 2; i++ {
+				_, err := client.DynamicEvaluationRunEpisode(context.Background(), &evaluation.EpisodeRequest{RunID: runID})
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.paths, paths)
+			assert.Equal(t, tt.bodyRun, bodyRun)
+		})
+	}
+}
+
+func TestEpisodeRouteFromVersion(t *testing.T) {
+	runID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	newRoute := "/dynamic_evaluation_run/" + runID.String() + "/episode"
+	oldRoute := "/dynamic_evaluation_run_episode"
+	tests := []struct {
+		name    string
+		version string
+		served  string
+		paths   []string
+	}{
+		{name: "old version", version: "2025.4.2", served: oldRoute, paths: []string{oldRoute}},
+		{name: "new version", version: "2025.6.0", served: newRoute, paths: []string{newRoute}},
+		{name: "unparsable version", version: "dev", served: oldRoute, paths: []string{newRoute, oldRoute}},
+		{name: "version without the route", version: "2025.6.0", served: oldRoute, paths: []string{newRoute, oldRoute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/status":
+					w.Write([]byte(`{"status": "ok", "version": "` + tt.version + `"}`))
+				case tt.served:
+					paths = append(paths, r.URL.Path)
+					w.Write([]byte(`{"episode_id": "550e8400-e29b-41d4-a716-446655440001"}`))
+				default:
+					paths = append(paths, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+			client := NewHTTPGateway(server.URL)
+
+			// The version reported by Status selects the route tried first
+			_, err := client.(HealthChecker).Status(context.Background())
+			require.NoError(t, err)
+			_, err = client.DynamicEvaluationRunEpisode(context.Background(), &evaluation.EpisodeRequest{RunID: runID})
+			require.NoError(t, err)
+			assert.Equal(t, tt.paths, paths)
+		})
+	}
+}
+
+func TestEpisodeRouteLabels(t *testing.T) {
+	oldRoute := "/dynamic_evaluation_run_episode"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != oldRoute {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"episode_id": "550e8400-e29b-41d4-a716-446655440001"}`))
+	}))
+	defer server.Close()
+
+	registry := metrics.NewRegistry()
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{ConsecutiveFailures: 1})
+	client := Chain(NewHTTPGateway(server.URL, WithCircuitBreaker(breaker)), MetricsInterceptor(registry))
+
+	_, err := client.DynamicEvaluationRunEpisode(context.Background(), &evaluation.EpisodeRequest{RunID: uuid.New()})
+	require.NoError(t, err)
+
+	// Metrics and circuits use the route the gateway served
+	assert.Equal(t, uint64(1), registry.HistogramCount(metrics.RequestDuration, metrics.Labels{
+		metrics.LabelOperation: string(OperationDynamicEvaluationRunEpisode),
+		metrics.LabelEndpoint:  oldRoute,
+		metrics.LabelStatus:    "ok",
+	}))
+	assert.Equal(t, map[CircuitKey]CircuitState{
+		{Endpoint: "/dynamic_evaluation_run/{run_id}/episode"}: CircuitClosed,
+		{Endpoint: oldRoute}: CircuitClosed,
+	}, breaker.States())
+}
+
+func TestEpisodeRouteKeepsGatewayNotFound(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Dynamic evaluation run not found"}`))
+	}))
+	defer server.Close()
+
+	// A 404 with a TensorZero error is the answer of a served route
+	_, err := NewHTTPGateway(server.URL).DynamicEvaluationRunEpisode(context.Background(), &evaluation.EpisodeRequest{RunID: uuid.New()})
+	var tzErr *tzerrors.TensorZeroError
+	require.ErrorAs(t, err, &tzErr)
+	assert.Equal(t, http.StatusNotFound, tzErr.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestParseGatewayVersion(t *testing.T) {
+	v, err := ParseGatewayVersion("2025.10.3")
+	require.NoError(t, err)
+	assert.Equal(t, GatewayVersion{Year: 2025, Month: 10, Patch: 3}, v)
+	assert.Equal(t, "2025.10.3", v.String())
+	assert.True(t, v.AtLeast(GatewayVersion{2025, 9, 9}))
+	assert.False(t, v.AtLeast(GatewayVersion{2026, 1, 0}))
+
+	for _, invalid := range []string{"", "2025.1", "2025.x.0", "2025.1.0-rc1"} {
+		_, err := ParseGatewayVersion(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
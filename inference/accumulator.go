@@ -0,0 +1,203 @@
+package inference
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/tool"
+	"github.com/google/uuid"
+)
+
+// Accumulator assembles the chunks of a stream into the final response.
+// Chat chunks produce a *ChatInferenceResponse, JSON chunks a *JsonInferenceResponse.
+//
+//	var acc inference.Accumulator
+//	for chunk, err := range stream.All() {
+//		if err != nil {
+//			return err
+//		}
+//		if err := acc.Add(chunk); err != nil {
+//			return err
+//		}
+//	}
+//	resp := acc.Response()
+type Accumulator struct {
+	inferenceID  uuid.UUID
+	episodeID    uuid.UUID
+	variantName  string
+	usage        shared.Usage
+	finishReason *FinishReason
+	chunks       int
+
+	// chat holds the content blocks in order of their first appearance
+	chat   []*blockBuilder
+	blocks map[blockKey]*blockBuilder
+
+	// json holds the raw output of JSON functions
+	json   []byte
+	isJSON bool
+}
+
+// blockKey identifies a content block of a chat stream. IDs are only unique per block type.
+type blockKey struct {
+	kind string
+	id   string
+}
+
+// blockBuilder collects the fragments of a single content block
+type blockBuilder struct {
+	kind      string
+	id        string
+	text      []byte
+	rawName   string
+	signature *string
+}
+
+// Add merges a chunk into the response
+func (a *Accumulator) Add(chunk InferenceChunk) error {
+	switch c := chunk.(type) {
+	case *ChatChunk:
+		if a.isJSON {
+			return fmt.Errorf("cannot add chat chunk to a JSON stream")
+		}
+		a.addMetadata(c.InferenceID, c.EpisodeID, c.VariantName, c.Usage, c.FinishReason)
+		for _, block := range c.Content {
+			if err := a.addBlock(block); err != nil {
+				return err
+			}
+		}
+	case *JsonChunk:
+		if len(a.chat) > 0 {
+			return fmt.Errorf("cannot add JSON chunk to a chat stream")
+		}
+		a.isJSON = true
+		a.addMetadata(c.InferenceID, c.EpisodeID, c.VariantName, c.Usage, c.FinishReason)
+		a.json = append(a.json, c.Raw...)
+	default:
+		return fmt.Errorf("unsupported chunk type %T", chunk)
+	}
+	return nil
+}
+
+// addMetadata keeps the identifiers of the first chunk and the last usage and finish reason
+func (a *Accumulator) addMetadata(inferenceID, episodeID uuid.UUID, variantName string, usage *shared.Usage, finishReason *FinishReason) {
+	if a.chunks == 0 {
+		a.inferenceID, a.episodeID, a.variantName = inferenceID, episodeID, variantName
+	}
+	a.chunks++
+	if usage != nil {
+		a.usage = *usage
+	}
+	if finishReason != nil {
+		a.finishReason = finishReason
+	}
+}
+
+// addBlock appends a content block chunk to the block with the same type and ID
+func (a *Accumulator) addBlock(chunk ContentBlockChunk) error {
+	var kind string
+	switch chunk.(type) {
+	case *shared.TextChunk:
+		kind = "text"
+	case *shared.ThoughtChunk:
+		kind = "thought"
+	case *tool.ToolCallChunk:
+		kind = "tool_call"
+	default:
+		return fmt.Errorf("unsupported content block chunk type %T", chunk)
+	}
+
+	key := blockKey{kind: kind, id: chunk.GetID()}
+	b, ok := a.blocks[key]
+	if !ok {
+		if a.blocks == nil {
+			a.blocks = make(map[blockKey]*blockBuilder)
+		}
+		b = &blockBuilder{kind: kind, id: key.id}
+		a.blocks[key] = b
+		a.chat = append(a.chat, b)
+	}
+
+	switch c := chunk.(type) {
+	case *shared.TextChunk:
+		b.text = append(b.text, c.Text...)
+	case *shared.ThoughtChunk:
+		b.text = append(b.text, c.Text...)
+		if c.Signature != nil {
+			b.signature = c.Signature
+		}
+	case *tool.ToolCallChunk:
+		b.text = append(b.text, c.RawArguments...)
+		b.rawName += c.RawName
+	}
+	return nil
+}
+
+// Response returns the response assembled from the chunks added so far, or
+// nil if no chunk has been added
+func (a *Accumulator) Response() InferenceResponse {
+	if a.chunks == 0 {
+		return nil
+	}
+	if a.isJSON {
+		return a.JsonResponse()
+	}
+	return a.ChatResponse()
+}
+
+// ChatResponse returns the chat response assembled from the chunks added so far.
+// Tool call arguments that are not a valid JSON object are only kept as raw arguments.
+func (a *Accumulator) ChatResponse() *ChatInferenceResponse {
+	resp := &ChatInferenceResponse{
+		InferenceID:  a.inferenceID,
+		EpisodeID:    a.episodeID,
+		VariantName:  a.variantName,
+		Content:      make([]shared.ContentBlock, 0, len(a.chat)),
+		Usage:        a.usage,
+		FinishReason: a.finishReason,
+	}
+
+	for _, b := range a.chat {
+		switch b.kind {
+		case "text":
+			resp.Content = append(resp.Content, shared.NewText(string(b.text)))
+		case "thought":
+			thought := shared.NewThought(string(b.text))
+			thought.Signature = b.signature
+			resp.Content = append(resp.Content, thought)
+		case "tool_call":
+			call := shared.NewToolCall(b.id, string(b.text), b.rawName)
+			if b.rawName != "" {
+				name := b.rawName
+				call.Name = &name
+			}
+			var arguments map[string]interface{}
+			if err := json.Unmarshal(b.text, &arguments); err == nil {
+				call.Arguments = arguments
+			}
+			resp.Content = append(resp.Content, call)
+		}
+	}
+	return resp
+}
+
+// JsonResponse returns the JSON response assembled from the chunks added so far.
+// Parsed is nil if the raw output is not a valid JSON object.
+func (a *Accumulator) JsonResponse() *JsonInferenceResponse {
+	raw := string(a.json)
+	resp := &JsonInferenceResponse{
+		InferenceID:  a.inferenceID,
+		EpisodeID:    a.episodeID,
+		VariantName:  a.variantName,
+		Output:       JsonInferenceOutput{Raw: &raw},
+		Usage:        a.usage,
+		FinishReason: a.finishReason,
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(a.json, &parsed); err == nil {
+		resp.Output.Parsed = parsed
+	}
+	return resp
+}
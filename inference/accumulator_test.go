@@ -0,0 +1,116 @@
+//go:build unit
+
+package inference
+
+import (
+	"testing"
+
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/tool"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccumulatorChat(t *testing.T) {
+	inferenceID, episodeID := uuid.New(), uuid.New()
+	stop := FinishReasonToolCall
+	chunks := []InferenceChunk{
+		&ChatChunk{InferenceID: inferenceID, EpisodeID: episodeID, VariantName: "v1", Content: []ContentBlockChunk{
+			&shared.ThoughtChunk{ID: "0", Text: "Let me ", Type: "thought"},
+		}},
+		&ChatChunk{InferenceID: inferenceID, EpisodeID: episodeID, VariantName: "v1", Content: []ContentBlockChunk{
+			&shared.ThoughtChunk{ID: "0", Text: "think", Type: "thought", Signature: util.StringPtr("sig")},
+			&shared.TextChunk{ID: "0", Text: "Hello", Type: "text"},
+		}},
+		&ChatChunk{InferenceID: inferenceID, EpisodeID: episodeID, VariantName: "v1", Content: []ContentBlockChunk{
+			&shared.TextChunk{ID: "0", Text: ", world", Type: "text"},
+			&tool.ToolCallChunk{ID: "call_1", RawName: "get_", RawArguments: `{"city":`, Type: "tool_call"},
+		}, Usage: &shared.Usage{InputTokens: 10, OutputTokens: 1}},
+		&ChatChunk{InferenceID: inferenceID, EpisodeID: episodeID, VariantName: "v1", Content: []ContentBlockChunk{
+			&tool.ToolCallChunk{ID: "call_1", RawName: "weather", RawArguments: `"Berlin"}`, Type: "tool_call"},
+		}, Usage: &shared.Usage{InputTokens: 10, OutputTokens: 8}, FinishReason: &stop},
+	}
+
+	var acc Accumulator
+	for _, chunk := range chunks {
+		require.NoError(t, acc.Add(chunk))
+	}
+
+	resp, ok := acc.Response().(*ChatInferenceResponse)
+	require.True(t, ok)
+	assert.Equal(t, inferenceID, resp.InferenceID)
+	assert.Equal(t, episodeID, resp.EpisodeID)
+	assert.Equal(t, "v1", resp.VariantName)
+	assert.Equal(t, shared.Usage{InputTokens: 10, OutputTokens: 8}, resp.Usage)
+	require.NotNil(t, resp.FinishReason)
+	assert.Equal(t, FinishReasonToolCall, *resp.FinishReason)
+
+	require.Len(t, resp.Content, 3)
+	thought, ok := resp.Content[0].(*shared.Thought)
+	require.True(t, ok)
+	assert.Equal(t, "Let me think", *thought.Text)
+	assert.Equal(t, "sig", *thought.Signature)
+
+	text, ok := resp.Content[1].(*shared.Text)
+	require.True(t, ok)
+	assert.Equal(t, "Hello, world", *text.Text)
+
+	call, ok := resp.Content[2].(*shared.ToolCall)
+	require.True(t, ok)
+	assert.Equal(t, "call_1", call.ID)
+	assert.Equal(t, "get_weather", call.RawName)
+	assert.Equal(t, `{"city":"Berlin"}`, call.RawArguments)
+	require.NotNil(t, call.Name)
+	assert.Equal(t, "get_weather", *call.Name)
+	assert.Equal(t, map[string]interface{}{"city": "Berlin"}, call.Arguments)
+}
+
+func TestAccumulatorInvalidToolArguments(t *testing.T) {
+	var acc Accumulator
+	require.NoError(t, acc.Add(&ChatChunk{Content: []ContentBlockChunk{
+		&tool.ToolCallChunk{ID: "call_1", RawName: "search", RawArguments: `{"q":`, Type: "tool_call"},
+	}}))
+
+	resp := acc.ChatResponse()
+	require.Len(t, resp.Content, 1)
+	call := resp.Content[0].(*shared.ToolCall)
+	assert.Equal(t, `{"q":`, call.RawArguments)
+	assert.Nil(t, call.Arguments)
+}
+
+func TestAccumulatorJson(t *testing.T) {
+	var acc Accumulator
+	require.NoError(t, acc.Add(&JsonChunk{VariantName: "v1", Raw: `{"answer":`}))
+	require.NoError(t, acc.Add(&JsonChunk{VariantName: "v1", Raw: `42}`, Usage: &shared.Usage{InputTokens: 5, OutputTokens: 3}}))
+
+	resp, ok := acc.Response().(*JsonInferenceResponse)
+	require.True(t, ok)
+	assert.Equal(t, "v1", resp.VariantName)
+	assert.Equal(t, `{"answer":42}`, *resp.Output.Raw)
+	assert.Equal(t, map[string]interface{}{"answer": float64(42)}, resp.Output.Parsed)
+	assert.Equal(t, 3, resp.Usage.OutputTokens)
+}
+
+func TestAccumulatorIncompleteJson(t *testing.T) {
+	var acc Accumulator
+	require.NoError(t, acc.Add(&JsonChunk{Raw: `{"answer":`}))
+
+	resp := acc.JsonResponse()
+	assert.Equal(t, `{"answer":`, *resp.Output.Raw)
+	assert.Nil(t, resp.Output.Parsed)
+}
+
+func TestAccumulatorErrors(t *testing.T) {
+	var acc Accumulator
+	assert.Nil(t, acc.Response())
+
+	require.NoError(t, acc.Add(&JsonChunk{Raw: "{"}))
+	assert.Error(t, acc.Add(&ChatChunk{Content: []ContentBlockChunk{&shared.TextChunk{ID: "0", Text: "hi"}}}))
+
+	var chat Accumulator
+	require.NoError(t, chat.Add(&ChatChunk{Content: []ContentBlockChunk{&shared.TextChunk{ID: "0", Text: "hi"}}}))
+	assert.Error(t, chat.Add(&JsonChunk{Raw: "{"}))
+	assert.Error(t, chat.Add(&ChatChunk{Content: []ContentBlockChunk{&MockContentBlockChunk{Type: "unknown", ID: "1"}}}))
+}
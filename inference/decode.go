@@ -0,0 +1,284 @@
+package inference
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/tool"
+	"github.com/google/uuid"
+)
+
+// responseJSON is the wire format of both inference response kinds. Chat
+// responses carry content, JSON responses carry output. Malformed IDs and
+// content blocks that are not objects are reported as errors.
+type responseJSON struct {
+	InferenceID      uuid.UUID            `json:"inference_id"`
+	EpisodeID        uuid.UUID            `json:"episode_id"`
+	VariantName      string               `json:"variant_name"`
+	Content          contentBlocksJSON    `json:"content"`
+	Output           *JsonInferenceOutput `json:"output"`
+	Usage            *shared.Usage        `json:"usage"`
+	FinishReason     *FinishReason        `json:"finish_reason"`
+	OriginalResponse *string              `json:"original_response"`
+}
+
+// contentBlocksJSON is the content of a chat response. It records whether the
+// field was present, so that a response with null content is still a chat response.
+// The blocks are decoded by the content block registry of the shared package.
+type contentBlocksJSON struct {
+	present bool
+	blocks  []json.RawMessage
+}
+
+func (c *contentBlocksJSON) UnmarshalJSON(data []byte) error {
+	c.present = true
+	return json.Unmarshal(data, &c.blocks)
+}
+
+// chunkJSON is the wire format of both inference chunk kinds. Chat chunks
+// carry content, JSON chunks carry raw.
+type chunkJSON struct {
+	InferenceID  uuid.UUID              `json:"inference_id"`
+	EpisodeID    uuid.UUID              `json:"episode_id"`
+	VariantName  string                 `json:"variant_name"`
+	Content      contentBlockChunksJSON `json:"content"`
+	Raw          *string                `json:"raw"`
+	Usage        *shared.Usage          `json:"usage"`
+	FinishReason *FinishReason          `json:"finish_reason"`
+}
+
+// contentBlockChunksJSON is the content of a chat chunk, see contentBlocksJSON
+type contentBlockChunksJSON struct {
+	present bool
+	blocks  []contentBlockChunkJSON
+}
+
+func (c *contentBlockChunksJSON) UnmarshalJSON(data []byte) error {
+	c.present = true
+	return json.Unmarshal(data, &c.blocks)
+}
+
+// contentBlockChunkJSON holds the fields of all content block chunk types
+type contentBlockChunkJSON struct {
+	Type         string  `json:"type"`
+	ID           *string `json:"id"`
+	Text         *string `json:"text"`
+	RawArguments *string `json:"raw_arguments"`
+	RawName      *string `json:"raw_name"`
+	Signature    *string `json:"signature"`
+}
+
+// DecodeInferenceResponse decodes a chat or JSON inference response, as
+// returned by the gateway or produced by json.Marshal, into
+// *ChatInferenceResponse or *JsonInferenceResponse
+func DecodeInferenceResponse(data []byte) (InferenceResponse, error) {
+	var raw responseJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	switch {
+	case raw.Content.present:
+		return raw.chatResponse()
+	case raw.Output != nil:
+		return raw.jsonResponse(), nil
+	}
+	return nil, fmt.Errorf("unable to determine response type")
+}
+
+// UnmarshalJSON decodes a chat inference response including its content blocks
+func (c *ChatInferenceResponse) UnmarshalJSON(data []byte) error {
+	var raw responseJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if !raw.Content.present && raw.Output != nil {
+		return fmt.Errorf("not a chat inference response: found output instead of content")
+	}
+	resp, err := raw.chatResponse()
+	if err != nil {
+		return err
+	}
+	*c = *resp
+	return nil
+}
+
+// UnmarshalJSON decodes a JSON inference response
+func (j *JsonInferenceResponse) UnmarshalJSON(data []byte) error {
+	var raw responseJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Content.present && raw.Output == nil {
+		return fmt.Errorf("not a JSON inference response: found content instead of output")
+	}
+	*j = *raw.jsonResponse()
+	return nil
+}
+
+func (r *responseJSON) chatResponse() (*ChatInferenceResponse, error) {
+	resp := &ChatInferenceResponse{
+		InferenceID:      r.InferenceID,
+		EpisodeID:        r.EpisodeID,
+		VariantName:      r.VariantName,
+		FinishReason:     r.FinishReason,
+		OriginalResponse: r.OriginalResponse,
+	}
+	if r.Usage != nil {
+		resp.Usage = *r.Usage
+	}
+	content, err := shared.DecodeContentBlocks(r.Content.blocks)
+	if err != nil {
+		return nil, err
+	}
+	resp.Content = content
+	return resp, nil
+}
+
+func (r *responseJSON) jsonResponse() *JsonInferenceResponse {
+	resp := &JsonInferenceResponse{
+		InferenceID:      r.InferenceID,
+		EpisodeID:        r.EpisodeID,
+		VariantName:      r.VariantName,
+		FinishReason:     r.FinishReason,
+		OriginalResponse: r.OriginalResponse,
+	}
+	if r.Usage != nil {
+		resp.Usage = *r.Usage
+	}
+	if r.Output != nil {
+		resp.Output = *r.Output
+	}
+	return resp
+}
+
+// DecodeInferenceChunk decodes a chat or JSON chunk of an inference stream
+// into *ChatChunk or *JsonChunk
+func DecodeInferenceChunk(data []byte) (InferenceChunk, error) {
+	var raw chunkJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chunk: %w", err)
+	}
+	switch {
+	case raw.Content.present:
+		return raw.chatChunk()
+	case raw.Raw != nil:
+		return raw.jsonChunk(), nil
+	}
+	return nil, fmt.Errorf("unable to determine chunk type")
+}
+
+// UnmarshalJSON decodes a chat chunk including its content block chunks
+func (c *ChatChunk) UnmarshalJSON(data []byte) error {
+	var raw chunkJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if !raw.Content.present && raw.Raw != nil {
+		return fmt.Errorf("not a chat chunk: found raw instead of content")
+	}
+	chunk, err := raw.chatChunk()
+	if err != nil {
+		return err
+	}
+	*c = *chunk
+	return nil
+}
+
+// UnmarshalJSON decodes a JSON chunk
+func (j *JsonChunk) UnmarshalJSON(data []byte) error {
+	var raw chunkJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Content.present && raw.Raw == nil {
+		return fmt.Errorf("not a JSON chunk: found content instead of raw")
+	}
+	*j = *raw.jsonChunk()
+	return nil
+}
+
+func (r *chunkJSON) chatChunk() (*ChatChunk, error) {
+	chunk := &ChatChunk{
+		InferenceID:  r.InferenceID,
+		EpisodeID:    r.EpisodeID,
+		VariantName:  r.VariantName,
+		Usage:        r.Usage,
+		FinishReason: r.FinishReason,
+	}
+	if r.Content.blocks != nil {
+		chunk.Content = make([]ContentBlockChunk, len(r.Content.blocks))
+	}
+	for i := range r.Content.blocks {
+		contentChunk, err := parseContentBlockChunk(&r.Content.blocks[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse content block chunk %d: %w", i, err)
+		}
+		chunk.Content[i] = contentChunk
+	}
+	return chunk, nil
+}
+
+func (r *chunkJSON) jsonChunk() *JsonChunk {
+	chunk := &JsonChunk{
+		InferenceID:  r.InferenceID,
+		EpisodeID:    r.EpisodeID,
+		VariantName:  r.VariantName,
+		Usage:        r.Usage,
+		FinishReason: r.FinishReason,
+	}
+	if r.Raw != nil {
+		chunk.Raw = *r.Raw
+	}
+	return chunk
+}
+
+// parseContentBlockChunk converts a decoded content block chunk into its typed form
+func parseContentBlockChunk(block *contentBlockChunkJSON) (ContentBlockChunk, error) {
+	if block.Type == "" {
+		return nil, fmt.Errorf("content block chunk missing type field")
+	}
+	if block.ID == nil {
+		return nil, fmt.Errorf("content block chunk missing id field")
+	}
+
+	switch block.Type {
+	case "text":
+		if block.Text == nil {
+			return nil, fmt.Errorf("text chunk missing text field")
+		}
+		return &shared.TextChunk{
+			ID:   *block.ID,
+			Text: *block.Text,
+			Type: block.Type,
+		}, nil
+
+	case "tool_call":
+		if block.RawArguments == nil {
+			return nil, fmt.Errorf("tool_call chunk missing raw_arguments field")
+		}
+		if block.RawName == nil {
+			return nil, fmt.Errorf("tool_call chunk missing raw_name field")
+		}
+		return &tool.ToolCallChunk{
+			ID:           *block.ID,
+			RawArguments: *block.RawArguments,
+			RawName:      *block.RawName,
+			Type:         block.Type,
+		}, nil
+
+	case "thought":
+		if block.Text == nil {
+			return nil, fmt.Errorf("thought chunk missing text field")
+		}
+		return &shared.ThoughtChunk{
+			ID:        *block.ID,
+			Text:      *block.Text,
+			Type:      block.Type,
+			Signature: block.Signature,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown content block chunk type: %s", block.Type)
+	}
+}
@@ -0,0 +1,307 @@
+//go:build unit
+
+package inference
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/tool"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testInferenceID = "550e8400-e29b-41d4-a716-446655440000"
+	testEpisodeID   = "550e8400-e29b-41d4-a716-446655440001"
+)
+
+func TestDecodeChatResponse(t *testing.T) {
+	data := `{"inference_id":"` + testInferenceID + `","episode_id":"` + testEpisodeID + `","variant_name":"v1",
+		"content":[
+			{"type":"text","text":"Hello"},
+			{"type":"thought","text":"Hmm","signature":"sig"},
+			{"type":"tool_call","id":"call_1","raw_name":"search","raw_arguments":"{\"q\":\"go\"}","name":"search","arguments":{"q":"go"}},
+			{"type":"image","url":"https://example.com/cat.png","mime_type":"image/png"},
+			{"type":"unknown","data":{"x":1},"model_provider_name":"openai"}
+		],
+		"usage":{"input_tokens":10,"output_tokens":5},"finish_reason":"tool_call","original_response":"{}"}`
+
+	resp, err := DecodeInferenceResponse([]byte(data))
+	require.NoError(t, err)
+	chat, ok := resp.(*ChatInferenceResponse)
+	require.True(t, ok)
+
+	assert.Equal(t, uuid.MustParse(testInferenceID), chat.InferenceID)
+	assert.Equal(t, uuid.MustParse(testEpisodeID), chat.EpisodeID)
+	assert.Equal(t, "v1", chat.VariantName)
+	assert.Equal(t, shared.Usage{InputTokens: 10, OutputTokens: 5}, chat.Usage)
+	assert.Equal(t, FinishReasonToolCall, *chat.FinishReason)
+	assert.Equal(t, "{}", *chat.OriginalResponse)
+
+	require.Len(t, chat.Content, 5)
+	assert.Equal(t, "Hello", *chat.Content[0].(*shared.Text).Text)
+	assert.Equal(t, "sig", *chat.Content[1].(*shared.Thought).Signature)
+	call := chat.Content[2].(*shared.ToolCall)
+	assert.Equal(t, "search", *call.Name)
+	assert.Equal(t, map[string]interface{}{"q": "go"}, call.Arguments)
+	assert.Equal(t, "image/png", *chat.Content[3].(*shared.ImageURL).MimeType)
+	assert.Equal(t, "openai", *chat.Content[4].(*shared.UnknownContentBlock).ModelProviderName)
+}
+
+func TestDecodeJsonResponse(t *testing.T) {
+	data := `{"inference_id":"` + testInferenceID + `","episode_id":"` + testEpisodeID + `","variant_name":"v1","output":{"raw":"{\"a\":1}","parsed":{"a":1}},"usage":{"input_tokens":1,"output_tokens":2}}`
+
+	resp, err := DecodeInferenceResponse([]byte(data))
+	require.NoError(t, err)
+	jsonResp, ok := resp.(*JsonInferenceResponse)
+	require.True(t, ok)
+	assert.Equal(t, `{"a":1}`, *jsonResp.Output.Raw)
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, jsonResp.Output.Parsed)
+	assert.Equal(t, 2, jsonResp.Usage.OutputTokens)
+}
+
+func TestDecodeResponseErrors(t *testing.T) {
+	tests := map[string]string{
+		"malformed inference_id": `{"inference_id":"not-a-uuid","content":[]}`,
+		"malformed episode_id":   `{"episode_id":"","output":{}}`,
+		"block is not an object": `{"content":["text"]}`,
+		"block without type":     `{"content":[{"text":"hi"}]}`,
+		"image without source":   `{"content":[{"type":"image"}]}`,
+		"tool call without id":   `{"content":[{"type":"tool_call","raw_name":"a","raw_arguments":"{}"}]}`,
+		"output is not object":   `{"output":"text"}`,
+		"no content or output":   `{"inference_id":"` + testInferenceID + `"}`,
+		"invalid JSON":           `{"content":`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				_, err := DecodeInferenceResponse([]byte(data))
+				assert.Error(t, err)
+			})
+		})
+	}
+}
+
+func TestDecodeChunks(t *testing.T) {
+	chunk, err := DecodeInferenceChunk([]byte(`{"inference_id":"` + testInferenceID + `","episode_id":"` + testEpisodeID + `","variant_name":"v1","content":[
+		{"type":"text","id":"0","text":"Hi"},
+		{"type":"thought","id":"1","text":"Hmm","signature":"sig"},
+		{"type":"tool_call","id":"call_1","raw_name":"search","raw_arguments":"{"}
+	],"usage":{"input_tokens":1,"output_tokens":2},"finish_reason":"stop"}`))
+	require.NoError(t, err)
+	chat, ok := chunk.(*ChatChunk)
+	require.True(t, ok)
+	assert.Equal(t, uuid.MustParse(testInferenceID), chat.InferenceID)
+	assert.Equal(t, []ContentBlockChunk{
+		&shared.TextChunk{ID: "0", Text: "Hi", Type: "text"},
+		&shared.ThoughtChunk{ID: "1", Text: "Hmm", Type: "thought", Signature: util.StringPtr("sig")},
+		&tool.ToolCallChunk{ID: "call_1", RawName: "search", RawArguments: "{", Type: "tool_call"},
+	}, chat.Content)
+	assert.Equal(t, &shared.Usage{InputTokens: 1, OutputTokens: 2}, chat.Usage)
+	assert.Equal(t, FinishReasonStop, *chat.FinishReason)
+
+	chunk, err = DecodeInferenceChunk([]byte(`{"inference_id":"` + testInferenceID + `","variant_name":"v1","raw":"{\"a\""}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a"`, chunk.(*JsonChunk).Raw)
+}
+
+func TestDecodeChunkErrors(t *testing.T) {
+	tests := map[string]string{
+		"malformed inference_id": `{"inference_id":"123","raw":""}`,
+		"block is not an object": `{"content":[42]}`,
+		"block without id":       `{"content":[{"type":"text","text":"hi"}]}`,
+		"text without text":      `{"content":[{"type":"text","id":"0"}]}`,
+		"unknown block type":     `{"content":[{"type":"image","id":"0"}]}`,
+		"no content or raw":      `{}`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				_, err := DecodeInferenceChunk([]byte(data))
+				assert.Error(t, err)
+			})
+		})
+	}
+}
+
+func TestChatResponseRoundTrip(t *testing.T) {
+	stop := FinishReasonToolCall
+	original := &ChatInferenceResponse{
+		InferenceID: uuid.MustParse(testInferenceID),
+		EpisodeID:   uuid.MustParse(testEpisodeID),
+		VariantName: "v1",
+		Content: []shared.ContentBlock{
+			shared.NewText("Hello"),
+			shared.NewTextWithArguments(map[string]interface{}{"name": "Ada"}),
+			shared.NewRawText("raw"),
+			shared.NewThought("Hmm"),
+			&shared.ToolCall{ID: "call_1", RawArguments: `{"q":"go"}`, RawName: "search", Arguments: map[string]interface{}{"q": "go"}, Name: util.StringPtr("search"), Type: "tool_call"},
+			tool.NewToolResult("search", "found", "call_1"),
+			shared.NewImageBase64("aGk=", "image/png"),
+			shared.NewImageURLWithMimeType("https://example.com/cat.png", "image/png"),
+			shared.NewFileBase64("aGk=", "application/pdf"),
+			shared.NewFileURL("https://example.com/doc.pdf"),
+			&shared.UnknownContentBlock{Data: map[string]interface{}{"x": float64(1)}, ModelProviderName: util.StringPtr("openai"), Type: "unknown"},
+		},
+		Usage:            shared.Usage{InputTokens: 10, OutputTokens: 5},
+		FinishReason:     &stop,
+		OriginalResponse: util.StringPtr("{}"),
+	}
+
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+
+	var decoded ChatInferenceResponse
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, original, &decoded)
+
+	resp, err := DecodeInferenceResponse(data)
+	require.NoError(t, err)
+	assert.Equal(t, original, resp)
+}
+
+func TestJsonResponseRoundTrip(t *testing.T) {
+	original := &JsonInferenceResponse{
+		InferenceID: uuid.MustParse(testInferenceID),
+		EpisodeID:   uuid.MustParse(testEpisodeID),
+		VariantName: "v1",
+		Output:      JsonInferenceOutput{Raw: util.StringPtr(`{"a":1}`), Parsed: map[string]interface{}{"a": float64(1)}},
+		Usage:       shared.Usage{InputTokens: 1, OutputTokens: 2},
+	}
+
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+
+	var decoded JsonInferenceResponse
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, original, &decoded)
+
+	resp, err := DecodeInferenceResponse(data)
+	require.NoError(t, err)
+	assert.Equal(t, original, resp)
+}
+
+func TestEmptyChatResponseRoundTrip(t *testing.T) {
+	data, err := json.Marshal(&ChatInferenceResponse{VariantName: "v1"})
+	require.NoError(t, err)
+
+	resp, err := DecodeInferenceResponse(data)
+	require.NoError(t, err)
+	assert.Equal(t, &ChatInferenceResponse{VariantName: "v1"}, resp)
+}
+
+func TestChunkRoundTrip(t *testing.T) {
+	stop := FinishReasonStop
+	chunks := []InferenceChunk{
+		&ChatChunk{
+			InferenceID: uuid.MustParse(testInferenceID),
+			EpisodeID:   uuid.MustParse(testEpisodeID),
+			VariantName: "v1",
+			Content: []ContentBlockChunk{
+				&shared.TextChunk{ID: "0", Text: "Hi", Type: "text"},
+				&shared.ThoughtChunk{ID: "1", Text: "Hmm", Type: "thought", Signature: util.StringPtr("sig")},
+				&tool.ToolCallChunk{ID: "call_1", RawName: "search", RawArguments: "{", Type: "tool_call"},
+			},
+			Usage:        &shared.Usage{InputTokens: 1, OutputTokens: 2},
+			FinishReason: &stop,
+		},
+		&ChatChunk{VariantName: "v1"},
+		&JsonChunk{InferenceID: uuid.MustParse(testInferenceID), VariantName: "v1", Raw: `{"a"`},
+	}
+
+	for _, original := range chunks {
+		data, err := json.Marshal(original)
+		require.NoError(t, err)
+
+		decoded, err := DecodeInferenceChunk(data)
+		require.NoError(t, err)
+		assert.Equal(t, original, decoded)
+
+		switch original.(type) {
+		case *ChatChunk:
+			var chunk ChatChunk
+			require.NoError(t, json.Unmarshal(data, &chunk))
+			assert.Equal(t, original, &chunk)
+		case *JsonChunk:
+			var chunk JsonChunk
+			require.NoError(t, json.Unmarshal(data, &chunk))
+			assert.Equal(t, original, &chunk)
+		}
+	}
+}
+
+func TestUnmarshalWrongKind(t *testing.T) {
+	var chat ChatInferenceResponse
+	assert.Error(t, json.Unmarshal([]byte(`{"output":{"raw":"{}"}}`), &chat))
+
+	var jsonResp JsonInferenceResponse
+	assert.Error(t, json.Unmarshal([]byte(`{"content":[]}`), &jsonResp))
+
+	var chatChunk ChatChunk
+	assert.Error(t, json.Unmarshal([]byte(`{"raw":"{"}`), &chatChunk))
+
+	var jsonChunk JsonChunk
+	assert.Error(t, json.Unmarshal([]byte(`{"content":[]}`), &jsonChunk))
+}
+
+func TestUnmarshalStoredResponses(t *testing.T) {
+	// Responses embedded in other values are decoded through UnmarshalJSON as well
+	var cached struct {
+		Key      string                 `json:"key"`
+		Response *ChatInferenceResponse `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"key":"k","response":{"content":[{"type":"text","text":"Hi"}]}}`), &cached))
+	require.Len(t, cached.Response.Content, 1)
+	assert.Equal(t, "Hi", *cached.Response.Content[0].(*shared.Text).Text)
+}
+
+// benchmarkChatChunks returns the payloads of a long chat stream
+func benchmarkChatChunks(n int) [][]byte {
+	chunks := make([][]byte, n)
+	for i := range chunks {
+		chunks[i] = []byte(fmt.Sprintf(`{"inference_id":"550e8400-e29b-41d4-a716-446655440000","episode_id":"550e8400-e29b-41d4-a716-446655440001","variant_name":"v1","content":[{"type":"text","id":"0","text":"token %d "}]}`, i))
+	}
+	return chunks
+}
+
+func BenchmarkDecodeChatChunks(b *testing.B) {
+	chunks := benchmarkChatChunks(1000)
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, data := range chunks {
+			if _, err := DecodeInferenceChunk(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecodeJsonResponse(b *testing.B) {
+	data := []byte(`{"inference_id":"550e8400-e29b-41d4-a716-446655440000","episode_id":"550e8400-e29b-41d4-a716-446655440001","variant_name":"v1","output":{"raw":"{\"items\":[` + strings.Repeat(`\"item\",`, 1000) + `\"last\"]}","parsed":{"items":[` + strings.Repeat(`"item",`, 1000) + `"last"]}},"usage":{"input_tokens":10,"output_tokens":1000}}`)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := DecodeInferenceResponse(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestStoredInferenceInputImages(t *testing.T) {
+	data := `{"id":"` + testInferenceID + `","episode_id":"` + testEpisodeID + `","function_name":"f","variant_name":"v1","timestamp":"2025-01-01T00:00:00Z",
+		"input":{"messages":[{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image","url":"https://example.com/cat.png"},{"type":"image","data":"aGk=","mime_type":"image/png"}]}]},
+		"output":[{"type":"text","text":"A cat"}]}`
+
+	var stored StoredInference
+	require.NoError(t, json.Unmarshal([]byte(data), &stored))
+	require.Len(t, stored.Input.Messages, 1)
+	content := stored.Input.Messages[0].Content
+	require.Len(t, content, 3)
+	assert.Equal(t, shared.NewImageURL("https://example.com/cat.png"), content[1])
+	assert.Equal(t, shared.NewImageBase64("aGk=", "image/png"), content[2])
+}
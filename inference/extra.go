@@ -0,0 +1,253 @@
+package inference
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+)
+
+// ExtraBody sets or deletes a field of the request body sent to model
+// providers. It applies to all variants and providers unless it is scoped with
+// ForVariant or ForProvider:
+//
+//	inference.SetExtraBody("/reasoning_effort", "high").ForVariant("o3")
+//	inference.DeleteExtraBody("/temperature").ForProvider("anthropic")
+type ExtraBody struct {
+	// VariantName restricts the entry to a variant
+	VariantName string
+
+	// ModelProviderName restricts the entry to a model provider
+	ModelProviderName string
+
+	// Pointer is the JSON Pointer (RFC 6901) of the field, e.g. "/thinking/budget_tokens"
+	Pointer string
+
+	// Value is the new value of the field. It is ignored if Delete is set.
+	Value interface{}
+
+	// Delete removes the field instead of setting it
+	Delete bool
+}
+
+// SetExtraBody sets the field at pointer to value
+func SetExtraBody(pointer string, value interface{}) ExtraBody {
+	return ExtraBody{Pointer: pointer, Value: value}
+}
+
+// DeleteExtraBody removes the field at pointer
+func DeleteExtraBody(pointer string) ExtraBody {
+	return ExtraBody{Pointer: pointer, Delete: true}
+}
+
+// ForVariant returns a copy of the entry that only applies to the given variant
+func (e ExtraBody) ForVariant(variantName string) ExtraBody {
+	e.VariantName = variantName
+	return e
+}
+
+// ForProvider returns a copy of the entry that only applies to the given model provider
+func (e ExtraBody) ForProvider(modelProviderName string) ExtraBody {
+	e.ModelProviderName = modelProviderName
+	return e
+}
+
+// Validate checks the scope and the JSON Pointer of the entry
+func (e ExtraBody) Validate() error {
+	if e.VariantName != "" && e.ModelProviderName != "" {
+		return tzerrors.NewValidationError("extra_body", "entry cannot be scoped to both a variant and a model provider")
+	}
+	if err := ValidateJSONPointer(e.Pointer); err != nil {
+		return tzerrors.NewValidationError("extra_body", err.Error())
+	}
+	return nil
+}
+
+// MarshalJSON encodes the entry in the format of the gateway. A set entry
+// always carries its value, so that a field can be set to null.
+func (e ExtraBody) MarshalJSON() ([]byte, error) {
+	entry := extraEntryJSON{
+		VariantName:       e.VariantName,
+		ModelProviderName: e.ModelProviderName,
+		Pointer:           e.Pointer,
+	}
+	if err := entry.setValue(e.Value, e.Delete); err != nil {
+		return nil, fmt.Errorf("failed to encode extra body value: %w", err)
+	}
+	return json.Marshal(entry)
+}
+
+// UnmarshalJSON decodes an entry in the format of the gateway
+func (e *ExtraBody) UnmarshalJSON(data []byte) error {
+	var entry extraEntryJSON
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	*e = ExtraBody{
+		VariantName:       entry.VariantName,
+		ModelProviderName: entry.ModelProviderName,
+		Pointer:           entry.Pointer,
+		Delete:            entry.Delete,
+	}
+	return entry.decodeValue(&e.Value)
+}
+
+// ExtraHeader sets or deletes a header of the request sent to model providers.
+// It applies to all variants and providers unless it is scoped with ForVariant
+// or ForProvider:
+//
+//	inference.SetExtraHeader("anthropic-beta", "output-128k-2025-02-19").ForProvider("anthropic")
+type ExtraHeader struct {
+	// VariantName restricts the entry to a variant
+	VariantName string
+
+	// ModelProviderName restricts the entry to a model provider
+	ModelProviderName string
+
+	// Name is the name of the header
+	Name string
+
+	// Value is the new value of the header. It is ignored if Delete is set.
+	Value string
+
+	// Delete removes the header instead of setting it
+	Delete bool
+}
+
+// SetExtraHeader sets the header name to value
+func SetExtraHeader(name, value string) ExtraHeader {
+	return ExtraHeader{Name: name, Value: value}
+}
+
+// DeleteExtraHeader removes the header name
+func DeleteExtraHeader(name string) ExtraHeader {
+	return ExtraHeader{Name: name, Delete: true}
+}
+
+// ForVariant returns a copy of the entry that only applies to the given variant
+func (h ExtraHeader) ForVariant(variantName string) ExtraHeader {
+	h.VariantName = variantName
+	return h
+}
+
+// ForProvider returns a copy of the entry that only applies to the given model provider
+func (h ExtraHeader) ForProvider(modelProviderName string) ExtraHeader {
+	h.ModelProviderName = modelProviderName
+	return h
+}
+
+// Validate checks the scope, the name and the value of the entry
+func (h ExtraHeader) Validate() error {
+	if h.VariantName != "" && h.ModelProviderName != "" {
+		return tzerrors.NewValidationError("extra_headers", "entry cannot be scoped to both a variant and a model provider")
+	}
+	if h.Name == "" {
+		return tzerrors.NewValidationError("extra_headers", "header name is required")
+	}
+	for _, r := range h.Name {
+		if !isTokenChar(r) {
+			return tzerrors.NewValidationError("extra_headers", fmt.Sprintf("invalid character %q in header name %q", r, h.Name))
+		}
+	}
+	if !h.Delete && strings.ContainsAny(h.Value, "\r\n\x00") {
+		return tzerrors.NewValidationError("extra_headers", fmt.Sprintf("invalid value for header %q", h.Name))
+	}
+	return nil
+}
+
+// MarshalJSON encodes the entry in the format of the gateway
+func (h ExtraHeader) MarshalJSON() ([]byte, error) {
+	entry := extraEntryJSON{
+		VariantName:       h.VariantName,
+		ModelProviderName: h.ModelProviderName,
+		Name:              h.Name,
+	}
+	if err := entry.setValue(h.Value, h.Delete); err != nil {
+		return nil, err
+	}
+	return json.Marshal(entry)
+}
+
+// UnmarshalJSON decodes an entry in the format of the gateway
+func (h *ExtraHeader) UnmarshalJSON(data []byte) error {
+	var entry extraEntryJSON
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	*h = ExtraHeader{
+		VariantName:       entry.VariantName,
+		ModelProviderName: entry.ModelProviderName,
+		Name:              entry.Name,
+		Delete:            entry.Delete,
+	}
+	if entry.Delete || entry.Value == nil {
+		return nil
+	}
+	if err := json.Unmarshal(entry.Value, &h.Value); err != nil {
+		return fmt.Errorf("extra header value must be a string: %w", err)
+	}
+	return nil
+}
+
+// extraEntryJSON is the wire format of extra_body and extra_headers entries.
+// The scope fields select a variant, a model provider or, if both are empty,
+// all of them.
+type extraEntryJSON struct {
+	VariantName       string          `json:"variant_name,omitempty"`
+	ModelProviderName string          `json:"model_provider_name,omitempty"`
+	Pointer           string          `json:"pointer,omitempty"`
+	Name              string          `json:"name,omitempty"`
+	Value             json.RawMessage `json:"value,omitempty"`
+	Delete            bool            `json:"delete,omitempty"`
+}
+
+func (e *extraEntryJSON) setValue(value interface{}, del bool) error {
+	if del {
+		e.Delete = true
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	e.Value = data
+	return nil
+}
+
+func (e *extraEntryJSON) decodeValue(value *interface{}) error {
+	if e.Delete || e.Value == nil {
+		return nil
+	}
+	return json.Unmarshal(e.Value, value)
+}
+
+// ValidateJSONPointer checks the syntax of a JSON Pointer (RFC 6901). The
+// pointer must refer to a field, so the empty pointer to the whole document
+// is rejected.
+func ValidateJSONPointer(pointer string) error {
+	if pointer == "" {
+		return fmt.Errorf("JSON pointer is required")
+	}
+	if pointer[0] != '/' {
+		return fmt.Errorf("JSON pointer %q must start with '/'", pointer)
+	}
+	for i := 0; i < len(pointer); i++ {
+		if pointer[i] != '~' {
+			continue
+		}
+		if i+1 == len(pointer) || (pointer[i+1] != '0' && pointer[i+1] != '1') {
+			return fmt.Errorf("JSON pointer %q has an invalid escape at offset %d; use ~0 for '~' and ~1 for '/'", pointer, i)
+		}
+	}
+	return nil
+}
+
+// isTokenChar reports whether r may appear in an HTTP header name (RFC 9110)
+func isTokenChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return r < 0x80 && strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
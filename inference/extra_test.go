@@ -0,0 +1,101 @@
+//go:build unit
+
+package inference
+
+import (
+	"encoding/json"
+	"testing"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtraBodyJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry ExtraBody
+		want  string
+	}{
+		{"global", SetExtraBody("/temperature", 0.2), `{"pointer":"/temperature","value":0.2}`},
+		{"variant", SetExtraBody("/reasoning_effort", "high").ForVariant("o3"), `{"variant_name":"o3","pointer":"/reasoning_effort","value":"high"}`},
+		{"provider", SetExtraBody("/thinking", map[string]interface{}{"type": "enabled"}).ForProvider("anthropic"), `{"model_provider_name":"anthropic","pointer":"/thinking","value":{"type":"enabled"}}`},
+		{"null value", SetExtraBody("/stop", nil), `{"pointer":"/stop","value":null}`},
+		{"delete", DeleteExtraBody("/top_p").ForVariant("v1"), `{"variant_name":"v1","pointer":"/top_p","delete":true}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.entry)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+
+			var decoded ExtraBody
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			again, err := json.Marshal(decoded)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(again))
+		})
+	}
+}
+
+func TestExtraHeaderJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry ExtraHeader
+		want  string
+	}{
+		{"global", SetExtraHeader("x-trace", "1"), `{"name":"x-trace","value":"1"}`},
+		{"variant", SetExtraHeader("x-trace", "1").ForVariant("v1"), `{"variant_name":"v1","name":"x-trace","value":"1"}`},
+		{"provider", DeleteExtraHeader("user-agent").ForProvider("openai"), `{"model_provider_name":"openai","name":"user-agent","delete":true}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.entry)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+
+			var decoded ExtraHeader
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			assert.Equal(t, tt.entry, decoded)
+		})
+	}
+
+	var h ExtraHeader
+	assert.Error(t, json.Unmarshal([]byte(`{"name":"x","value":1}`), &h))
+}
+
+func TestValidateJSONPointer(t *testing.T) {
+	for _, pointer := range []string{"/", "/a", "/a/b/0", "/a~1b", "/m~0n", "/a/"} {
+		assert.NoError(t, ValidateJSONPointer(pointer), pointer)
+	}
+	for _, pointer := range []string{"", "a", "#/a", "/a~", "/a~2", "/~x"} {
+		assert.Error(t, ValidateJSONPointer(pointer), pointer)
+	}
+}
+
+func TestExtraValidate(t *testing.T) {
+	var validationErr *tzerrors.ValidationError
+
+	assert.NoError(t, SetExtraBody("/a", 1).ForProvider("p").Validate())
+	require.ErrorAs(t, SetExtraBody("a", 1).Validate(), &validationErr)
+	assert.Equal(t, "extra_body", validationErr.Field)
+	assert.Error(t, SetExtraBody("/a", 1).ForVariant("v").ForProvider("p").Validate())
+
+	assert.NoError(t, SetExtraHeader("X-Api-Key", "secret").Validate())
+	assert.NoError(t, DeleteExtraHeader("X-Api-Key").ForVariant("v").Validate())
+	require.ErrorAs(t, SetExtraHeader("", "x").Validate(), &validationErr)
+	assert.Equal(t, "extra_headers", validationErr.Field)
+	assert.Error(t, SetExtraHeader("X Api", "x").Validate())
+	assert.Error(t, SetExtraHeader("X-Api", "a\r\nInjected: 1").Validate())
+	assert.Error(t, SetExtraHeader("X-Api", "x").ForVariant("v").ForProvider("p").Validate())
+
+	req := &InferenceRequest{
+		ExtraBody:    []ExtraBody{SetExtraBody("/a", 1)},
+		ExtraHeaders: []ExtraHeader{SetExtraHeader("bad header", "x")},
+	}
+	assert.Error(t, req.Validate())
+	req.ExtraHeaders = nil
+	assert.NoError(t, req.Validate())
+}
@@ -20,19 +20,25 @@ const (
 	FinishReasonUnknown       FinishReason = "unknown"
 )
 
-// StoredInference represents a stored inference from the list API
+// StoredInference represents a stored inference from the list API.
+// Output holds []shared.ContentBlock for chat functions and JsonInferenceOutput
+// for JSON functions; use ChatOutput and JsonOutput to access it.
 type StoredInference struct {
 	ID             uuid.UUID              `json:"id"`
 	EpisodeID      uuid.UUID              `json:"episode_id"`
 	FunctionName   string                 `json:"function_name"`
 	VariantName    string                 `json:"variant_name"`
+	Type           FunctionType           `json:"type,omitempty"`
 	Input          InferenceInput         `json:"input"`
 	Output         interface{}            `json:"output"`
+	OutputSource   InferenceOutputSource  `json:"output_source,omitempty"`
+	DispatchType   DispatchType           `json:"dispatch_type,omitempty"`
 	ToolParams     *tool.ToolParams       `json:"tool_params,omitempty"`
+	ExtraBody      []ExtraBody            `json:"extra_body,omitempty"`
 	ProcessingTime *float64               `json:"processing_time,omitempty"`
 	Timestamp      string                 `json:"timestamp"` // RFC 3339
 	Tags           map[string]string      `json:"tags,omitempty"`
-	MetricValues   map[string]interface{} `json:"metric_values,omitempty"`
+	MetricValues   map[string]MetricValue `json:"metric_values,omitempty"` // see MetricValue
 }
 
 // InferenceResponse represents either chat or JSON inference response
@@ -113,10 +119,9 @@ type InferenceRequest struct {
 	// This allows real-time processing of partial responses as they're generated.
 	Stream *bool `json:"stream,omitempty"`
 
-	// Params allows dynamic override of inference parameters at runtime.
-	// Format: {"variant_type": {"param": value, ...}, ...}
-	// Prefer setting these in configuration when possible.
-	Params map[string]interface{} `json:"params,omitempty"`
+	// Params allows dynamic override of inference parameters at runtime,
+	// grouped by variant type. Prefer setting these in configuration when possible.
+	Params *InferenceParams `json:"params,omitempty"`
 
 	// VariantName optionally pins the request to a specific variant.
 	// This is not recommended for production use and is primarily for
@@ -144,8 +149,8 @@ type InferenceRequest struct {
 	AdditionalTools []map[string]interface{} `json:"additional_tools,omitempty"`
 
 	// ToolChoice overrides the tool choice strategy for this request.
-	// Supported strategies: "none", "auto", "required", or specific tool selection.
-	ToolChoice tool.ToolChoice `json:"tool_choice,omitempty"`
+	// Use tool.Auto, tool.Required, tool.None or tool.Specific to create it.
+	ToolChoice tool.ToolChoice `json:"tool_choice,omitzero"`
 
 	// ParallelToolCalls, when true, allows multiple tool calls in a single turn.
 	// Only supported by certain providers (OpenAI, Fireworks AI). Defaults to
@@ -167,9 +172,9 @@ type InferenceRequest struct {
 	// credentials at inference time.
 	Credentials map[string]string `json:"credentials,omitempty"`
 
-	// CacheOptions controls inference caching behavior with options like
-	// enabled mode ("write_only", "read_only", "on", "off") and max_age_s.
-	CacheOptions map[string]interface{} `json:"cache_options,omitempty"`
+	// CacheOptions controls inference caching behavior: the cache mode
+	// ("write_only", "read_only", "on", "off") and the maximum age of cached responses.
+	CacheOptions *CacheOptions `json:"cache_options,omitempty"`
 
 	// ExtraBody allows modification of the request body sent to model providers.
 	// This is an advanced "escape hatch" for provider-specific functionality
@@ -179,13 +184,73 @@ type InferenceRequest struct {
 	// ExtraHeaders allows modification of request headers sent to model providers.
 	// This is an advanced "escape hatch" for provider-specific functionality
 	// not yet implemented in TensorZero.
-	ExtraHeaders []map[string]interface{} `json:"extra_headers,omitempty"`
+	ExtraHeaders []ExtraHeader `json:"extra_headers,omitempty"`
 
 	// IncludeOriginalResponse, when true, includes the original model provider
 	// response in the response as a string. Useful for debugging and analysis.
 	IncludeOriginalResponse *bool `json:"include_original_response,omitempty"`
 }
 
+// Validate checks the request on the client side before it is sent
+func (r *InferenceRequest) Validate() error {
+	if err := r.ValidateToolChoice(); err != nil {
+		return err
+	}
+	if r.Params != nil {
+		if err := r.Params.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.CacheOptions != nil {
+		if err := r.CacheOptions.Validate(); err != nil {
+			return err
+		}
+	}
+	return r.ValidateExtras()
+}
+
+// ValidateExtras checks the extra_body and extra_headers entries
+func (r *InferenceRequest) ValidateExtras() error {
+	for _, e := range r.ExtraBody {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, h := range r.ExtraHeaders {
+		if err := h.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateToolChoice checks that a specific tool choice names an allowed or additional tool
+func (r *InferenceRequest) ValidateToolChoice() error {
+	additional := make([]interface{}, len(r.AdditionalTools))
+	for i, t := range r.AdditionalTools {
+		additional[i] = t
+	}
+	return r.ToolChoice.Validate(r.AllowedTools, toolNames(additional))
+}
+
+// toolNames returns the names of tools given as tool.Tool values or as maps
+func toolNames(tools []interface{}) []string {
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		switch t := t.(type) {
+		case tool.Tool:
+			names = append(names, t.Name)
+		case *tool.Tool:
+			names = append(names, t.Name)
+		case map[string]interface{}:
+			if name, ok := t["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
 // InferenceInput represents the input data for an inference request.
 // This contains the messages and system prompts that will be sent to the AI model.
 type InferenceInput struct {
@@ -207,13 +272,18 @@ type ChatDatapointInsert struct {
 	Output            interface{}       `json:"output,omitempty"`
 	AllowedTools      []string          `json:"allowed_tools,omitempty"`
 	AdditionalTools   []interface{}     `json:"additional_tools,omitempty"`
-	ToolChoice        *string           `json:"tool_choice,omitempty"`
+	ToolChoice        tool.ToolChoice   `json:"tool_choice,omitzero"`
 	ParallelToolCalls *bool             `json:"parallel_tool_calls,omitempty"`
 	Tags              map[string]string `json:"tags,omitempty"`
 }
 
 func (c *ChatDatapointInsert) GetFunctionName() string { return c.FunctionName }
 
+// ValidateToolChoice checks that a specific tool choice names an allowed or additional tool
+func (c *ChatDatapointInsert) ValidateToolChoice() error {
+	return c.ToolChoice.Validate(c.AllowedTools, toolNames(c.AdditionalTools))
+}
+
 // JsonDatapointInsert represents JSON datapoint insertion
 type JsonDatapointInsert struct {
 	FunctionName string            `json:"function_name"`
@@ -225,10 +295,6 @@ type JsonDatapointInsert struct {
 
 func (j *JsonDatapointInsert) GetFunctionName() string { return j.FunctionName }
 
-// ExtraBody represents a custom field to be added to the inference request body
-type ExtraBody struct {
-}
-
 // ListInferencesRequest represents a request to list stored inferences with filtering and pagination.
 // This allows you to retrieve and analyze historical inference data for monitoring and evaluation.
 type ListInferencesRequest struct {
@@ -259,6 +325,15 @@ type ListInferencesRequest struct {
 	// Offset optionally specifies the number of inferences to skip before
 	// starting to return results. Used for pagination in combination with Limit.
 	Offset *int `json:"offset,omitempty"`
+
+	// OutputSource selects whether the returned output is the one generated by the
+	// model or the latest demonstration. Defaults to InferenceOutputSourceInference.
+	OutputSource *InferenceOutputSource `json:"output_source,omitempty"`
+
+	// MetricTypes optionally maps metric names to the types of the metric
+	// configuration, to type the metric values of the returned inferences.
+	// It is not sent to the gateway.
+	MetricTypes map[string]MetricType `json:"-"`
 }
 
 // ChatInferenceResponse represents the response from a chat function inference.
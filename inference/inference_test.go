@@ -5,10 +5,12 @@ package inference
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/denkhaus/tensorzero/filter"
 	"github.com/denkhaus/tensorzero/shared"
 	"github.com/denkhaus/tensorzero/tool"
+	"github.com/denkhaus/tensorzero/util"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
@@ -67,20 +69,20 @@ func TestInferenceRequest(t *testing.T) {
 	modelName := "gpt-4"
 
 	stream := true
-	params := map[string]interface{}{"temp": 0.5}
+	params := &InferenceParams{ChatCompletion: &ChatCompletionParams{Temperature: util.Float64Ptr(0.5)}}
 	variantName := "default"
 	dryrun := false
 	outputSchema := map[string]interface{}{"type": "string"}
 	allowedTools := []string{"tool1"}
 	additionalTools := []map[string]interface{}{{"name": "tool2"}}
-	toolChoice := tool.ToolChoice("auto")
+	toolChoice := tool.Auto()
 	parallelToolCalls := true
 	internal := false
 	tags := map[string]string{"source": "test"}
 	credentials := map[string]string{"key": "value"}
-	cacheOptions := map[string]interface{}{"ttl": 300}
-	extraBody := []ExtraBody{}
-	extraHeaders := []map[string]interface{}{{"X-Custom": "header"}}
+	cacheOptions := &CacheOptions{Enabled: CacheModeOn, MaxAge: 5 * time.Minute}
+	extraBody := []ExtraBody{SetExtraBody("/temperature", 0.5)}
+	extraHeaders := []ExtraHeader{SetExtraHeader("X-Custom", "header")}
 	includeOriginalResponse := true
 
 	episodeID, err := uuid.NewV7()
@@ -292,7 +294,7 @@ func TestStoredInference(t *testing.T) {
 	output := "Answer"
 	timestamp := "2023-01-01T12:00:00Z"
 	tags := map[string]string{"stage": "prod"}
-	metricValues := map[string]interface{}{"accuracy": 0.9}
+	metricValues := map[string]MetricValue{"accuracy": FloatMetricValue(0.9)}
 
 	inf := StoredInference{
 		ID:           infID,
@@ -379,3 +381,30 @@ func TestJsonChunk(t *testing.T) {
 	assert.Equal(t, &shared.Usage{InputTokens: 2, OutputTokens: 2}, chunk.Usage)
 	assert.Equal(t, &finishReason, chunk.FinishReason)
 }
+
+func TestValidateToolChoice(t *testing.T) {
+	req := &InferenceRequest{
+		AllowedTools:    []string{"search"},
+		AdditionalTools: []map[string]interface{}{{"name": "lookup"}},
+		ToolChoice:      tool.Specific("lookup"),
+	}
+	assert.NoError(t, req.ValidateToolChoice())
+
+	req.ToolChoice = tool.Specific("delete")
+	assert.Error(t, req.ValidateToolChoice())
+
+	dp := &ChatDatapointInsert{
+		AllowedTools:    []string{"search"},
+		AdditionalTools: []interface{}{tool.Tool{Name: "lookup"}},
+		ToolChoice:      tool.Specific("lookup"),
+	}
+	assert.NoError(t, dp.ValidateToolChoice())
+
+	data, err := json.Marshal(dp)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"tool_choice":{"specific":"lookup"}`)
+
+	var decoded ChatDatapointInsert
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, tool.Specific("lookup"), decoded.ToolChoice)
+}
@@ -68,9 +68,97 @@ func WithAdditionalTools(additionalTools []map[string]interface{}) InferenceRequ
 }
 
 // WithParams sets the params for the inference request
-func WithParams(params map[string]interface{}) InferenceRequestOption {
+func WithParams(params InferenceParams) InferenceRequestOption {
 	return func(g *InferenceRequest) {
-		g.Params = params
+		// The other options change the chat_completion params in place, so the
+		// request gets its own copy
+		p := params
+		if p.ChatCompletion != nil {
+			chatCompletion := *p.ChatCompletion
+			p.ChatCompletion = &chatCompletion
+		}
+		g.Params = &p
+	}
+}
+
+// chatCompletionParams returns the chat_completion params of the request, creating them if needed
+func chatCompletionParams(g *InferenceRequest) *ChatCompletionParams {
+	if g.Params == nil {
+		g.Params = &InferenceParams{}
+	}
+	if g.Params.ChatCompletion == nil {
+		g.Params.ChatCompletion = &ChatCompletionParams{}
+	}
+	return g.Params.ChatCompletion
+}
+
+// WithTemperature sets the temperature for chat_completion variants
+func WithTemperature(temperature float64) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).Temperature = &temperature
+	}
+}
+
+// WithMaxTokens sets the maximum number of output tokens for chat_completion variants
+func WithMaxTokens(maxTokens int) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).MaxTokens = &maxTokens
+	}
+}
+
+// WithSeed sets the sampling seed for chat_completion variants
+func WithSeed(seed int) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).Seed = &seed
+	}
+}
+
+// WithTopP sets the nucleus sampling probability for chat_completion variants
+func WithTopP(topP float64) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).TopP = &topP
+	}
+}
+
+// WithPresencePenalty sets the presence penalty for chat_completion variants
+func WithPresencePenalty(penalty float64) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).PresencePenalty = &penalty
+	}
+}
+
+// WithFrequencyPenalty sets the frequency penalty for chat_completion variants
+func WithFrequencyPenalty(penalty float64) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).FrequencyPenalty = &penalty
+	}
+}
+
+// WithJsonMode sets the JSON mode for chat_completion variants
+func WithJsonMode(mode JsonMode) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).JsonMode = &mode
+	}
+}
+
+// WithStopSequences sets the stop sequences for chat_completion variants
+func WithStopSequences(stopSequences []string) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).StopSequences = stopSequences
+	}
+}
+
+// WithReasoningEffort sets the reasoning effort for chat_completion variants
+func WithReasoningEffort(effort ReasoningEffort) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).ReasoningEffort = &effort
+	}
+}
+
+// WithThinkingBudgetTokens sets the thinking token budget for chat_completion variants
+func WithThinkingBudgetTokens(budget int) InferenceRequestOption {
+	return func(g *InferenceRequest) {
+		chatCompletionParams(g).ThinkingBudgetTokens = &budget
 	}
 }
 
@@ -117,9 +205,9 @@ func WithCredentials(credentials map[string]string) InferenceRequestOption {
 }
 
 // WithCacheOptions sets the cache options for the inference request
-func WithCacheOptions(cacheOptions map[string]interface{}) InferenceRequestOption {
+func WithCacheOptions(cacheOptions CacheOptions) InferenceRequestOption {
 	return func(g *InferenceRequest) {
-		g.CacheOptions = cacheOptions
+		g.CacheOptions = &cacheOptions
 	}
 }
 
@@ -131,7 +219,7 @@ func WithExtraBody(extraBody []ExtraBody) InferenceRequestOption {
 }
 
 // WithExtraHeaders sets the extra headers for the inference request
-func WithExtraHeaders(extraHeaders []map[string]interface{}) InferenceRequestOption {
+func WithExtraHeaders(extraHeaders []ExtraHeader) InferenceRequestOption {
 	return func(g *InferenceRequest) {
 		g.ExtraHeaders = extraHeaders
 	}
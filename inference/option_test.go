@@ -4,8 +4,11 @@ package inference
 
 import (
 	"testing"
+	"time"
 
+	"github.com/denkhaus/tensorzero/shared"
 	"github.com/denkhaus/tensorzero/tool"
+	"github.com/denkhaus/tensorzero/util"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
@@ -78,10 +81,10 @@ func TestWithAdditionalTools(t *testing.T) {
 
 func TestWithParams(t *testing.T) {
 	req := &InferenceRequest{}
-	params := map[string]interface{}{"temp": 0.7}
+	params := InferenceParams{ChatCompletion: &ChatCompletionParams{Temperature: util.Float64Ptr(0.7)}}
 	WithParams(params)(req)
 	assert.NotNil(t, req.Params)
-	assert.Equal(t, params, req.Params)
+	assert.Equal(t, params, *req.Params)
 }
 
 func TestWithVariantName(t *testing.T) {
@@ -94,7 +97,7 @@ func TestWithVariantName(t *testing.T) {
 
 func TestWithToolChoice(t *testing.T) {
 	req := &InferenceRequest{}
-	choice := tool.ToolChoice("auto")
+	choice := tool.Auto()
 	WithToolChoice(choice)(req)
 	assert.Equal(t, choice, req.ToolChoice)
 }
@@ -131,15 +134,15 @@ func TestWithCredentials(t *testing.T) {
 
 func TestWithCacheOptions(t *testing.T) {
 	req := &InferenceRequest{}
-	options := map[string]interface{}{"ttl": 3600}
+	options := CacheOptions{Enabled: CacheModeReadOnly, MaxAge: time.Hour}
 	WithCacheOptions(options)(req)
 	assert.NotNil(t, req.CacheOptions)
-	assert.Equal(t, options, req.CacheOptions)
+	assert.Equal(t, options, *req.CacheOptions)
 }
 
 func TestWithExtraHeaders(t *testing.T) {
 	req := &InferenceRequest{}
-	headers := []map[string]interface{}{{"Auth": "Bearer token"}}
+	headers := []ExtraHeader{SetExtraHeader("Auth", "Bearer token")}
 	WithExtraHeaders(headers)(req)
 	assert.NotNil(t, req.ExtraHeaders)
 	assert.Equal(t, headers, req.ExtraHeaders)
@@ -160,8 +163,10 @@ func TestWithUserMessage(t *testing.T) {
 	assert.Len(t, req.Input.Messages, 1)
 	assert.Equal(t, "user", req.Input.Messages[0].Role)
 	assert.Len(t, req.Input.Messages[0].Content, 1)
-	assert.Equal(t, "text", req.Input.Messages[0].Content[0].Type)
-	assert.Equal(t, prompt, req.Input.Messages[0].Content[0].Text)
+	text, ok := req.Input.Messages[0].Content[0].(*shared.Text)
+	assert.True(t, ok)
+	assert.Equal(t, "text", text.Type)
+	assert.Equal(t, prompt, *text.Text)
 }
 
 func TestWithSystemMessage(t *testing.T) {
@@ -172,8 +177,10 @@ func TestWithSystemMessage(t *testing.T) {
 	assert.Len(t, req.Input.Messages, 1)
 	assert.Equal(t, "system", req.Input.Messages[0].Role)
 	assert.Len(t, req.Input.Messages[0].Content, 1)
-	assert.Equal(t, "text", req.Input.Messages[0].Content[0].Type)
-	assert.Equal(t, content, req.Input.Messages[0].Content[0].Text)
+	text, ok := req.Input.Messages[0].Content[0].(*shared.Text)
+	assert.True(t, ok)
+	assert.Equal(t, "text", text.Type)
+	assert.Equal(t, content, *text.Text)
 
 	// Test prepending to existing messages
 	WithUserMessage("Hello!")(req)
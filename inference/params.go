@@ -0,0 +1,149 @@
+package inference
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+)
+
+// CacheMode controls whether an inference reads from and writes to the cache
+type CacheMode string
+
+const (
+	CacheModeOn        CacheMode = "on"
+	CacheModeOff       CacheMode = "off"
+	CacheModeReadOnly  CacheMode = "read_only"
+	CacheModeWriteOnly CacheMode = "write_only"
+)
+
+// CacheOptions controls the inference cache of the gateway
+type CacheOptions struct {
+	// Enabled selects the cache mode. The gateway defaults to write_only.
+	Enabled CacheMode
+
+	// MaxAge is the maximum age of cached responses. It is sent in whole
+	// seconds, rounded up; zero accepts cached responses of any age.
+	MaxAge time.Duration
+}
+
+// Validate checks the cache mode and the maximum age
+func (c CacheOptions) Validate() error {
+	switch c.Enabled {
+	case "", CacheModeOn, CacheModeOff, CacheModeReadOnly, CacheModeWriteOnly:
+	default:
+		return tzerrors.NewValidationError("cache_options.enabled", fmt.Sprintf("unknown cache mode %q", c.Enabled))
+	}
+	if c.MaxAge < 0 {
+		return tzerrors.NewValidationError("cache_options.max_age_s", "max age must not be negative")
+	}
+	return nil
+}
+
+type cacheOptionsJSON struct {
+	Enabled CacheMode `json:"enabled,omitempty"`
+	MaxAgeS *int64    `json:"max_age_s,omitempty"`
+}
+
+// MarshalJSON encodes the options as {"enabled": ..., "max_age_s": ...}
+func (c CacheOptions) MarshalJSON() ([]byte, error) {
+	raw := cacheOptionsJSON{Enabled: c.Enabled}
+	if c.MaxAge > 0 {
+		seconds := int64((c.MaxAge + time.Second - 1) / time.Second)
+		raw.MaxAgeS = &seconds
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON decodes options in the format of the gateway
+func (c *CacheOptions) UnmarshalJSON(data []byte) error {
+	var raw cacheOptionsJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*c = CacheOptions{Enabled: raw.Enabled}
+	if raw.MaxAgeS != nil {
+		c.MaxAge = time.Duration(*raw.MaxAgeS) * time.Second
+	}
+	return nil
+}
+
+// InferenceParams overrides inference parameters per variant type
+type InferenceParams struct {
+	// ChatCompletion applies to chat_completion variants
+	ChatCompletion *ChatCompletionParams `json:"chat_completion,omitempty"`
+}
+
+// Validate checks the parameters of all variant types
+func (p InferenceParams) Validate() error {
+	if p.ChatCompletion != nil {
+		return p.ChatCompletion.Validate()
+	}
+	return nil
+}
+
+// JsonMode selects how JSON functions ask the model for JSON output
+type JsonMode string
+
+const (
+	JsonModeOff    JsonMode = "off"
+	JsonModeOn     JsonMode = "on"
+	JsonModeStrict JsonMode = "strict"
+	JsonModeTool   JsonMode = "tool"
+)
+
+// ReasoningEffort controls how much reasoning models think before answering.
+// Levels not listed here are passed through, so that levels added by newer
+// gateways and providers can be used.
+type ReasoningEffort string
+
+const (
+	ReasoningEffortMinimal ReasoningEffort = "minimal"
+	ReasoningEffortLow     ReasoningEffort = "low"
+	ReasoningEffortMedium  ReasoningEffort = "medium"
+	ReasoningEffortHigh    ReasoningEffort = "high"
+)
+
+// ChatCompletionParams overrides the configuration of chat_completion variants.
+// Unset fields keep their configured values.
+type ChatCompletionParams struct {
+	Temperature          *float64         `json:"temperature,omitempty"`
+	MaxTokens            *int             `json:"max_tokens,omitempty"`
+	Seed                 *int             `json:"seed,omitempty"`
+	TopP                 *float64         `json:"top_p,omitempty"`
+	PresencePenalty      *float64         `json:"presence_penalty,omitempty"`
+	FrequencyPenalty     *float64         `json:"frequency_penalty,omitempty"`
+	JsonMode             *JsonMode        `json:"json_mode,omitempty"`
+	StopSequences        []string         `json:"stop_sequences,omitempty"`
+	ReasoningEffort      *ReasoningEffort `json:"reasoning_effort,omitempty"`
+	ThinkingBudgetTokens *int             `json:"thinking_budget_tokens,omitempty"`
+}
+
+// Validate checks the ranges of the parameters
+func (p ChatCompletionParams) Validate() error {
+	if p.Temperature != nil && *p.Temperature < 0 {
+		return tzerrors.NewValidationError("params.chat_completion.temperature", "temperature must not be negative")
+	}
+	if p.MaxTokens != nil && *p.MaxTokens <= 0 {
+		return tzerrors.NewValidationError("params.chat_completion.max_tokens", "max tokens must be positive")
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		return tzerrors.NewValidationError("params.chat_completion.top_p", "top_p must be between 0 and 1")
+	}
+	if p.JsonMode != nil {
+		switch *p.JsonMode {
+		case JsonModeOff, JsonModeOn, JsonModeStrict, JsonModeTool:
+		default:
+			return tzerrors.NewValidationError("params.chat_completion.json_mode", fmt.Sprintf("unknown JSON mode %q", *p.JsonMode))
+		}
+	}
+	if p.ReasoningEffort != nil && *p.ReasoningEffort == "" {
+		// Levels other than the known ones are left to the gateway to check
+		return tzerrors.NewValidationError("params.chat_completion.reasoning_effort", "reasoning effort must not be empty")
+	}
+	if p.ThinkingBudgetTokens != nil && *p.ThinkingBudgetTokens <= 0 {
+		return tzerrors.NewValidationError("params.chat_completion.thinking_budget_tokens", "thinking budget must be positive")
+	}
+	return nil
+}
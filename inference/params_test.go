@@ -0,0 +1,135 @@
+//go:build unit
+
+package inference
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheOptionsJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CacheOptions
+		want    string
+	}{
+		{"empty", CacheOptions{}, `{}`},
+		{"mode", CacheOptions{Enabled: CacheModeWriteOnly}, `{"enabled":"write_only"}`},
+		{"max age", CacheOptions{Enabled: CacheModeOn, MaxAge: time.Hour}, `{"enabled":"on","max_age_s":3600}`},
+		{"rounded up", CacheOptions{Enabled: CacheModeReadOnly, MaxAge: 1500 * time.Millisecond}, `{"enabled":"read_only","max_age_s":2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.options)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+		})
+	}
+
+	var options CacheOptions
+	require.NoError(t, json.Unmarshal([]byte(`{"enabled":"off","max_age_s":60}`), &options))
+	assert.Equal(t, CacheOptions{Enabled: CacheModeOff, MaxAge: time.Minute}, options)
+}
+
+func TestCacheOptionsValidate(t *testing.T) {
+	assert.NoError(t, CacheOptions{}.Validate())
+	assert.NoError(t, CacheOptions{Enabled: CacheModeOn, MaxAge: time.Minute}.Validate())
+
+	var validationErr *tzerrors.ValidationError
+	require.ErrorAs(t, CacheOptions{Enabled: "yes"}.Validate(), &validationErr)
+	assert.Equal(t, "cache_options.enabled", validationErr.Field)
+	assert.Error(t, CacheOptions{MaxAge: -time.Second}.Validate())
+}
+
+func TestChatCompletionParamsOptions(t *testing.T) {
+	req := &InferenceRequest{}
+	for _, opt := range []InferenceRequestOption{
+		WithTemperature(0.2),
+		WithMaxTokens(256),
+		WithSeed(42),
+		WithTopP(0.9),
+		WithPresencePenalty(0.1),
+		WithFrequencyPenalty(0.3),
+		WithJsonMode(JsonModeStrict),
+		WithStopSequences([]string{"\n\n"}),
+		WithReasoningEffort(ReasoningEffortHigh),
+		WithThinkingBudgetTokens(1024),
+	} {
+		opt(req)
+	}
+
+	data, err := json.Marshal(req.Params)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"chat_completion":{
+		"temperature":0.2,"max_tokens":256,"seed":42,"top_p":0.9,
+		"presence_penalty":0.1,"frequency_penalty":0.3,"json_mode":"strict",
+		"stop_sequences":["\n\n"],"reasoning_effort":"high","thinking_budget_tokens":1024
+	}}`, string(data))
+	assert.NoError(t, req.Validate())
+}
+
+func TestWithParamsCopiesChatCompletion(t *testing.T) {
+	params := InferenceParams{ChatCompletion: &ChatCompletionParams{}}
+
+	withParams := WithParams(params)
+	first := &InferenceRequest{}
+	withParams(first)
+	WithTemperature(0.5)(first)
+
+	second := &InferenceRequest{}
+	withParams(second)
+
+	// Neither the caller's params nor other requests see the temperature
+	assert.Nil(t, params.ChatCompletion.Temperature)
+	assert.Nil(t, second.Params.ChatCompletion.Temperature)
+	require.NotNil(t, first.Params.ChatCompletion.Temperature)
+	assert.Equal(t, 0.5, *first.Params.ChatCompletion.Temperature)
+}
+
+func TestChatCompletionParamsUnknownReasoningEffort(t *testing.T) {
+	req := &InferenceRequest{}
+	WithReasoningEffort("xhigh")(req)
+	assert.NoError(t, req.Validate())
+}
+
+func TestChatCompletionParamsZeroValues(t *testing.T) {
+	req := &InferenceRequest{}
+	WithTemperature(0)(req)
+	WithSeed(0)(req)
+
+	data, err := json.Marshal(req.Params)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"chat_completion":{"temperature":0,"seed":0}}`, string(data))
+}
+
+func TestChatCompletionParamsValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		opt   InferenceRequestOption
+		field string
+	}{
+		{"negative temperature", WithTemperature(-1), "params.chat_completion.temperature"},
+		{"zero max tokens", WithMaxTokens(0), "params.chat_completion.max_tokens"},
+		{"top_p above one", WithTopP(1.5), "params.chat_completion.top_p"},
+		{"unknown json mode", WithJsonMode("loose"), "params.chat_completion.json_mode"},
+		{"empty reasoning effort", WithReasoningEffort(""), "params.chat_completion.reasoning_effort"},
+		{"negative thinking budget", WithThinkingBudgetTokens(-1), "params.chat_completion.thinking_budget_tokens"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &InferenceRequest{}
+			tt.opt(req)
+
+			var validationErr *tzerrors.ValidationError
+			require.ErrorAs(t, req.Validate(), &validationErr)
+			assert.Equal(t, tt.field, validationErr.Field)
+		})
+	}
+}
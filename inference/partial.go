@@ -0,0 +1,488 @@
+package inference
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// PartialJSON incrementally parses the raw output of a streaming JSON function.
+// Every write only scans the new bytes; Value and Decode complete the buffer by
+// closing open strings, arrays and objects and dropping members whose key or
+// value has not arrived yet, so the output can be shown while it is streaming.
+//
+//	var p inference.PartialJSON
+//	for chunk, err := range stream.All() {
+//		...
+//		p.Add(chunk.(*inference.JsonChunk))
+//		obj, _ := p.Object()
+//		render(obj, p.CompletedFields())
+//	}
+type PartialJSON struct {
+	buf   []byte
+	err   error
+	state partialState
+	stack []partialFrame
+
+	// memberStart is the length the buffer is cut to when the member or
+	// element being parsed has to be dropped
+	memberStart int
+
+	// key collects the raw bytes of the object key being parsed
+	key []byte
+
+	// tokenStart is the offset of the string, number or literal being parsed
+	tokenStart int
+
+	// escaping is set inside an escape sequence of a string, which starts at escapeStart
+	escaping    bool
+	escapeStart int
+	unicodeLeft int
+
+	complete  map[string]bool
+	completed []string
+}
+
+type partialState uint8
+
+const (
+	partialValue        partialState = iota // expecting a value
+	partialKeyOrEnd                         // after '{', expecting a key or '}'
+	partialKey                              // after ',' in an object, expecting a key
+	partialInKey                            // inside an object key
+	partialColon                            // after a key, expecting ':'
+	partialInString                         // inside a string value
+	partialInNumber                         // inside a number
+	partialInLiteral                        // inside true, false or null
+	partialAfterValue                       // after a value, expecting ',' or a closing bracket
+	partialElementOrEnd                     // after '[', expecting a value or ']'
+	partialDone                             // the top-level value is complete
+)
+
+// partialFrame is an open object or array
+type partialFrame struct {
+	kind        byte
+	key         string
+	index       int
+	memberStart int
+}
+
+// Add appends the raw fragment of a JSON chunk
+func (p *PartialJSON) Add(chunk *JsonChunk) error {
+	_, err := p.WriteString(chunk.Raw)
+	return err
+}
+
+// Write appends raw output to the buffer. It fails once the output is not valid JSON.
+func (p *PartialJSON) Write(raw []byte) (int, error) {
+	return len(raw), p.scan(raw)
+}
+
+// WriteString appends raw output to the buffer
+func (p *PartialJSON) WriteString(raw string) (int, error) {
+	return len(raw), p.scan([]byte(raw))
+}
+
+// Raw returns the raw output written so far
+func (p *PartialJSON) Raw() string {
+	return string(p.buf)
+}
+
+// Done reports whether the top-level value is complete
+func (p *PartialJSON) Done() bool {
+	return p.err == nil && p.state == partialDone
+}
+
+// IsComplete reports whether the value at the given JSON Pointer (RFC 6901) is
+// complete, for example "/entities/0/name". The empty pointer refers to the
+// whole document.
+func (p *PartialJSON) IsComplete(pointer string) bool {
+	if pointer == "" {
+		return p.Done()
+	}
+	return p.complete[pointer]
+}
+
+// CompletedFields returns the names of the top-level fields whose values are
+// complete, in the order they were completed
+func (p *PartialJSON) CompletedFields() []string {
+	return append([]string(nil), p.completed...)
+}
+
+// Bytes returns the best-effort completion of the output as valid JSON, or nil
+// if no value has started yet
+func (p *PartialJSON) Bytes() ([]byte, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	var out []byte
+	switch p.state {
+	case partialDone, partialAfterValue, partialKeyOrEnd, partialElementOrEnd:
+		out = append(out, p.buf...)
+	case partialInString:
+		end := len(p.buf)
+		if p.escaping {
+			end = p.escapeStart
+		}
+		end = trimIncompleteRune(p.buf[p.tokenStart+1:end]) + p.tokenStart + 1
+		out = append(append(out, p.buf[:end]...), '"')
+	case partialInNumber:
+		if last := p.buf[len(p.buf)-1]; last >= '0' && last <= '9' {
+			out = append(out, p.buf...)
+		} else {
+			out = append(out, p.buf[:p.memberStart]...)
+		}
+	case partialInLiteral:
+		out = append(out, p.buf...)
+		out = append(out, literalCompletion(p.buf[p.tokenStart:])[len(p.buf)-p.tokenStart:]...)
+	default:
+		out = append(out, p.buf[:p.memberStart]...)
+	}
+
+	if len(p.stack) == 0 && len(strings.TrimSpace(string(out))) == 0 {
+		return nil, nil
+	}
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i].kind == '{' {
+			out = append(out, '}')
+		} else {
+			out = append(out, ']')
+		}
+	}
+	return out, nil
+}
+
+// Value returns the best-effort partial value, or nil if no value has started yet
+func (p *PartialJSON) Value() (interface{}, error) {
+	var v interface{}
+	if err := p.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Object returns the best-effort partial object, or nil if the object has not started yet
+func (p *PartialJSON) Object() (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	if err := p.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Decode stores the best-effort partial value in v. Fields that have not
+// started yet are left untouched.
+func (p *PartialJSON) Decode(v interface{}) error {
+	data, err := p.Bytes()
+	if err != nil || data == nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode partial JSON: %w", err)
+	}
+	return nil
+}
+
+// scan advances the parser over the new bytes
+func (p *PartialJSON) scan(raw []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	for _, c := range raw {
+		pos := len(p.buf)
+		p.buf = append(p.buf, c)
+		if err := p.step(c, pos); err != nil {
+			p.err = fmt.Errorf("invalid JSON at offset %d: %w", pos, err)
+			return p.err
+		}
+	}
+	return nil
+}
+
+func (p *PartialJSON) step(c byte, pos int) error {
+	switch p.state {
+	case partialInString, partialInKey:
+		return p.stepString(c)
+	case partialInNumber:
+		if isNumberByte(c) {
+			return nil
+		}
+		if err := p.endToken(); err != nil {
+			return err
+		}
+		return p.step(c, pos)
+	case partialInLiteral:
+		if c >= 'a' && c <= 'z' {
+			if literalCompletion(p.buf[p.tokenStart:]) == "" {
+				return fmt.Errorf("invalid literal %q", p.buf[p.tokenStart:])
+			}
+			return nil
+		}
+		if err := p.endToken(); err != nil {
+			return err
+		}
+		return p.step(c, pos)
+	}
+
+	if isSpace(c) {
+		return nil
+	}
+
+	switch p.state {
+	case partialValue, partialElementOrEnd:
+		if c == ']' && p.state == partialElementOrEnd {
+			return p.closeContainer(']')
+		}
+		return p.startValue(c, pos)
+	case partialKeyOrEnd, partialKey:
+		if c == '}' && p.state == partialKeyOrEnd {
+			return p.closeContainer('}')
+		}
+		if c != '"' {
+			return fmt.Errorf("expected object key, got %q", c)
+		}
+		p.state = partialInKey
+		p.tokenStart = pos
+		p.key = append(p.key[:0], c)
+		return nil
+	case partialColon:
+		if c != ':' {
+			return fmt.Errorf("expected ':', got %q", c)
+		}
+		p.state = partialValue
+		return nil
+	case partialAfterValue:
+		frame := &p.stack[len(p.stack)-1]
+		switch {
+		case c == ',' && frame.kind == '{':
+			p.memberStart = pos
+			p.state = partialKey
+		case c == ',' && frame.kind == '[':
+			p.memberStart = pos
+			frame.index++
+			p.state = partialValue
+		case c == '}' || c == ']':
+			return p.closeContainer(c)
+		default:
+			return fmt.Errorf("expected ',' or closing bracket, got %q", c)
+		}
+		return nil
+	case partialDone:
+		return fmt.Errorf("unexpected %q after top-level value", c)
+	}
+	return nil
+}
+
+// startValue begins a value at pos
+func (p *PartialJSON) startValue(c byte, pos int) error {
+	p.tokenStart = pos
+	switch {
+	case c == '{' || c == '[':
+		p.stack = append(p.stack, partialFrame{kind: c, memberStart: p.memberStart})
+		p.memberStart = pos + 1
+		if c == '{' {
+			p.state = partialKeyOrEnd
+		} else {
+			p.state = partialElementOrEnd
+		}
+	case c == '"':
+		p.state = partialInString
+	case c == '-' || (c >= '0' && c <= '9'):
+		p.state = partialInNumber
+	case c == 't' || c == 'f' || c == 'n':
+		p.state = partialInLiteral
+	default:
+		return fmt.Errorf("unexpected %q", c)
+	}
+	return nil
+}
+
+// stepString advances over a byte of a string value or key
+func (p *PartialJSON) stepString(c byte) error {
+	if p.state == partialInKey {
+		p.key = append(p.key, c)
+	}
+	switch {
+	case p.unicodeLeft > 0:
+		if !isHexByte(c) {
+			return fmt.Errorf("invalid unicode escape")
+		}
+		p.unicodeLeft--
+		p.escaping = p.unicodeLeft > 0
+	case p.escaping:
+		if c == 'u' {
+			p.unicodeLeft = 4
+			return nil
+		}
+		if !strings.ContainsRune(`"\/bfnrt`, rune(c)) {
+			return fmt.Errorf("invalid escape %q", c)
+		}
+		p.escaping = false
+	case c == '\\':
+		p.escaping = true
+		p.escapeStart = len(p.buf) - 1
+	case c == '"':
+		if p.state == partialInString {
+			return p.endValue()
+		}
+		var key string
+		if err := json.Unmarshal(p.key, &key); err != nil {
+			return err
+		}
+		p.stack[len(p.stack)-1].key = key
+		p.state = partialColon
+	case c < 0x20:
+		return fmt.Errorf("control character in string")
+	}
+	return nil
+}
+
+// endToken finishes a number or literal at the first byte that does not belong to it
+func (p *PartialJSON) endToken() error {
+	token := p.buf[p.tokenStart : len(p.buf)-1]
+	var v interface{}
+	if err := json.Unmarshal(token, &v); err != nil {
+		return fmt.Errorf("invalid token %q", token)
+	}
+	return p.endValue()
+}
+
+// closeContainer closes the innermost object or array with c
+func (p *PartialJSON) closeContainer(c byte) error {
+	frame := p.stack[len(p.stack)-1]
+	if (frame.kind == '{') != (c == '}') {
+		return fmt.Errorf("unexpected %q", c)
+	}
+	p.stack = p.stack[:len(p.stack)-1]
+	p.memberStart = frame.memberStart
+	return p.endValue()
+}
+
+// endValue records that the value being parsed is complete
+func (p *PartialJSON) endValue() error {
+	if len(p.stack) == 0 {
+		p.state = partialDone
+		return nil
+	}
+	if p.complete == nil {
+		p.complete = make(map[string]bool)
+	}
+	p.complete[p.pointer()] = true
+	if len(p.stack) == 1 && p.stack[0].kind == '{' {
+		p.completed = append(p.completed, p.stack[0].key)
+	}
+	p.state = partialAfterValue
+	return nil
+}
+
+// pointer returns the JSON Pointer of the value being parsed
+func (p *PartialJSON) pointer() string {
+	var sb strings.Builder
+	for _, frame := range p.stack {
+		sb.WriteByte('/')
+		if frame.kind == '{' {
+			sb.WriteString(pointerEscaper.Replace(frame.key))
+		} else {
+			sb.WriteString(strconv.Itoa(frame.index))
+		}
+	}
+	return sb.String()
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// literalCompletion returns the literal that starts with token, or "" if there is none
+func literalCompletion(token []byte) string {
+	for _, literal := range []string{"true", "false", "null"} {
+		if strings.HasPrefix(literal, string(token)) {
+			return literal
+		}
+	}
+	return ""
+}
+
+// trimIncompleteRune returns the length of b without a trailing partial UTF-8 sequence
+func trimIncompleteRune(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isNumberByte(c byte) bool {
+	return (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+}
+
+func isHexByte(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// Partial is a best-effort snapshot of the output of a streaming JSON function
+type Partial[T any] struct {
+	// Value holds the fields received so far
+	Value T
+
+	// Completed lists the JSON names of the top-level fields whose values are complete
+	Completed []string
+
+	// Done reports whether the whole output has been received
+	Done bool
+}
+
+// IsComplete reports whether the top-level field with the given JSON name is complete
+func (p Partial[T]) IsComplete(field string) bool {
+	for _, name := range p.Completed {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// PartialDecoder decodes the output of a streaming JSON function into T after every chunk
+//
+//	var dec inference.PartialDecoder[Entities]
+//	for chunk, err := range stream.All() {
+//		...
+//		partial, err := dec.Add(chunk.(*inference.JsonChunk))
+//		render(partial.Value, partial.Completed)
+//	}
+type PartialDecoder[T any] struct {
+	parser PartialJSON
+}
+
+// Add appends the raw fragment of a JSON chunk and returns the current snapshot
+func (d *PartialDecoder[T]) Add(chunk *JsonChunk) (Partial[T], error) {
+	return d.WriteString(chunk.Raw)
+}
+
+// WriteString appends raw output and returns the current snapshot
+func (d *PartialDecoder[T]) WriteString(raw string) (Partial[T], error) {
+	if _, err := d.parser.WriteString(raw); err != nil {
+		return Partial[T]{}, err
+	}
+	return d.Snapshot()
+}
+
+// Snapshot decodes the output received so far into a fresh T
+func (d *PartialDecoder[T]) Snapshot() (Partial[T], error) {
+	var partial Partial[T]
+	if err := d.parser.Decode(&partial.Value); err != nil {
+		return Partial[T]{}, err
+	}
+	partial.Completed = d.parser.CompletedFields()
+	partial.Done = d.parser.Done()
+	return partial, nil
+}
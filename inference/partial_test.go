@@ -0,0 +1,191 @@
+//go:build unit
+
+package inference
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartialJSONCompletion(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{``, ``},
+		{`  `, ``},
+		{`{`, `{}`},
+		{`{"na`, `{}`},
+		{`{"name"`, `{}`},
+		{`{"name":`, `{}`},
+		{`{"name": "Ada`, `{"name": "Ada"}`},
+		{`{"name": "Ada", `, `{"name": "Ada"}`},
+		{`{"name": "Ada", "age": 3`, `{"name": "Ada", "age": 3}`},
+		{`{"name": "Ada", "age": -`, `{"name": "Ada"}`},
+		{`{"name": "Ada", "age": 3.`, `{"name": "Ada"}`},
+		{`{"ok": tr`, `{"ok": true}`},
+		{`{"ok": n`, `{"ok": null}`},
+		{`{"tags": [`, `{"tags": []}`},
+		{`{"tags": ["a", "b`, `{"tags": ["a", "b"]}`},
+		{`{"tags": ["a",`, `{"tags": ["a"]}`},
+		{`{"tags": [{"x": 1}, {"y"`, `{"tags": [{"x": 1}, {}]}`},
+		{`{"text": "line\`, `{"text": "line"}`},
+		{`{"text": "caf\u00`, `{"text": "caf"}`},
+		{`{"text": "café`, `{"text": "café"}`},
+		{"{\"text\": \"caf\xc3", `{"text": "caf"}`},
+		{`{"a": {"b": [1, 2]}}`, `{"a": {"b": [1, 2]}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			var p PartialJSON
+			_, err := p.WriteString(tt.raw)
+			require.NoError(t, err)
+
+			data, err := p.Bytes()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+			if data != nil {
+				assert.True(t, json.Valid(data))
+			}
+		})
+	}
+}
+
+func TestPartialJSONEmptyContainers(t *testing.T) {
+	for _, raw := range []string{`{}`, `{"a":{}}`, `[{}]`, `{"a":[]}`, `[]`, `{"a": {}, "b": [[], {}]}`} {
+		t.Run(raw, func(t *testing.T) {
+			var whole PartialJSON
+			_, err := whole.WriteString(raw)
+			require.NoError(t, err)
+			assert.True(t, whole.Done())
+			data, err := whole.Bytes()
+			require.NoError(t, err)
+			assert.JSONEq(t, raw, string(data))
+
+			var bytewise PartialJSON
+			for i := 0; i < len(raw); i++ {
+				_, err := bytewise.WriteString(raw[i : i+1])
+				require.NoError(t, err)
+				data, err := bytewise.Bytes()
+				require.NoError(t, err)
+				require.True(t, json.Valid(data), "invalid completion %q of %q", data, raw[:i+1])
+			}
+			assert.True(t, bytewise.Done())
+			data, err = bytewise.Bytes()
+			require.NoError(t, err)
+			assert.JSONEq(t, raw, string(data))
+		})
+	}
+}
+
+func TestPartialJSONByteByByte(t *testing.T) {
+	raw := `{"entities": [{"name": "Ada Lovelace", "type": "person"}, {"name": "London", "type": "place"}], "count": 2, "done": true}`
+
+	var p PartialJSON
+	for i := 0; i < len(raw); i++ {
+		_, err := p.WriteString(raw[i : i+1])
+		require.NoError(t, err)
+		data, err := p.Bytes()
+		require.NoError(t, err)
+		if data != nil {
+			require.True(t, json.Valid(data), "invalid completion %q of %q", data, raw[:i+1])
+		}
+	}
+
+	assert.True(t, p.Done())
+	obj, err := p.Object()
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), obj["count"])
+	assert.Equal(t, []string{"entities", "count", "done"}, p.CompletedFields())
+	assert.True(t, p.IsComplete("/entities/1/type"))
+	assert.True(t, p.IsComplete(""))
+}
+
+func TestPartialJSONCompletedFields(t *testing.T) {
+	var p PartialJSON
+	require.NoError(t, p.Add(&JsonChunk{Raw: `{"title": "Report", "entities": [{"name": "Ada"}, {"na`}))
+
+	assert.Equal(t, []string{"title"}, p.CompletedFields())
+	assert.True(t, p.IsComplete("/title"))
+	assert.True(t, p.IsComplete("/entities/0"))
+	assert.True(t, p.IsComplete("/entities/0/name"))
+	assert.False(t, p.IsComplete("/entities/1"))
+	assert.False(t, p.IsComplete("/entities"))
+	assert.False(t, p.Done())
+
+	obj, err := p.Object()
+	require.NoError(t, err)
+	assert.Equal(t, "Report", obj["title"])
+	assert.Len(t, obj["entities"], 2)
+}
+
+func TestPartialJSONEscapedKeys(t *testing.T) {
+	var p PartialJSON
+	_, err := p.WriteString(`{"a/b~c": 1, "d\"e": 2}`)
+	require.NoError(t, err)
+
+	assert.True(t, p.IsComplete("/a~1b~0c"))
+	assert.Equal(t, []string{"a/b~c", `d"e`}, p.CompletedFields())
+}
+
+func TestPartialJSONInvalid(t *testing.T) {
+	for _, raw := range []string{`{"a" 1}`, `{"a": 1]`, `{"a": tx`, `[1,]`, `{"a": 1} x`, `{1: 2}`, `{"a": 01,`} {
+		t.Run(raw, func(t *testing.T) {
+			var p PartialJSON
+			_, err := p.WriteString(raw)
+			assert.Error(t, err)
+
+			// The parser stays failed
+			_, err = p.WriteString(`}`)
+			assert.Error(t, err)
+			_, err = p.Object()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPartialDecoder(t *testing.T) {
+	type entity struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	type entities struct {
+		People []entity `json:"people"`
+		Places []entity `json:"places"`
+		Count  int      `json:"count"`
+	}
+
+	var dec PartialDecoder[entities]
+	partial, err := dec.Add(&JsonChunk{Raw: `{"people": [{"name": "Ada", "type": "per`})
+	require.NoError(t, err)
+	require.Len(t, partial.Value.People, 1)
+	assert.Equal(t, "Ada", partial.Value.People[0].Name)
+	assert.Equal(t, "per", partial.Value.People[0].Type)
+	assert.Empty(t, partial.Completed)
+	assert.False(t, partial.Done)
+
+	partial, err = dec.Add(&JsonChunk{Raw: `son"}], "places": [], "count": 1`})
+	require.NoError(t, err)
+	assert.Equal(t, "person", partial.Value.People[0].Type)
+	assert.Equal(t, 1, partial.Value.Count)
+	assert.Equal(t, []string{"people", "places"}, partial.Completed)
+	assert.True(t, partial.IsComplete("places"))
+	assert.False(t, partial.IsComplete("count"))
+
+	partial, err = dec.Add(&JsonChunk{Raw: `}`})
+	require.NoError(t, err)
+	assert.True(t, partial.Done)
+	assert.True(t, partial.IsComplete("count"))
+}
+
+func TestPartialDecoderTypeMismatch(t *testing.T) {
+	var dec PartialDecoder[struct {
+		Count int `json:"count"`
+	}]
+	_, err := dec.WriteString(`{"count": "many"`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,223 @@
+package inference
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/denkhaus/tensorzero/shared"
+)
+
+// FunctionType is the type of the function a stored inference was made for
+type FunctionType string
+
+const (
+	FunctionTypeChat FunctionType = "chat"
+	FunctionTypeJson FunctionType = "json"
+)
+
+// InferenceOutputSource selects the output returned for stored inferences
+type InferenceOutputSource string
+
+const (
+	// InferenceOutputSourceInference returns the output generated by the model
+	InferenceOutputSourceInference InferenceOutputSource = "inference"
+
+	// InferenceOutputSourceDemonstration returns the latest demonstration
+	// submitted as feedback, if there is one
+	InferenceOutputSourceDemonstration InferenceOutputSource = "demonstration"
+)
+
+// DispatchType reports how the gateway dispatched a stored inference, for
+// example as a regular or as a batch inference. Values are passed through as
+// reported by the gateway.
+type DispatchType string
+
+// ChatOutput returns the output of an inference of a chat function
+func (s *StoredInference) ChatOutput() ([]shared.ContentBlock, bool) {
+	content, ok := s.Output.([]shared.ContentBlock)
+	return content, ok
+}
+
+// JsonOutput returns the output of an inference of a JSON function
+func (s *StoredInference) JsonOutput() (JsonInferenceOutput, bool) {
+	output, ok := s.Output.(JsonInferenceOutput)
+	return output, ok
+}
+
+// UnmarshalJSON decodes a stored inference. The output is decoded according to
+// the function type, or to its shape if the gateway did not report the type.
+func (s *StoredInference) UnmarshalJSON(data []byte) error {
+	// storedInference has the fields but not the methods of StoredInference
+	type storedInference StoredInference
+	var raw struct {
+		storedInference
+		Output json.RawMessage `json:"output"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*s = StoredInference(raw.storedInference)
+	s.Output = nil
+
+	output := bytes.TrimSpace(raw.Output)
+	if len(output) == 0 || bytes.Equal(output, []byte("null")) {
+		return nil
+	}
+
+	kind := s.Type
+	if kind == "" {
+		kind = FunctionTypeJson
+		if output[0] == '[' {
+			kind = FunctionTypeChat
+		}
+	}
+
+	switch kind {
+	case FunctionTypeChat:
+		var blocks []json.RawMessage
+		if err := json.Unmarshal(output, &blocks); err != nil {
+			return fmt.Errorf("failed to decode chat output: %w", err)
+		}
+		content, err := shared.DecodeContentBlocks(blocks)
+		if err != nil {
+			return fmt.Errorf("failed to decode chat output: %w", err)
+		}
+		s.Output = content
+	case FunctionTypeJson:
+		var jsonOutput JsonInferenceOutput
+		if err := json.Unmarshal(output, &jsonOutput); err != nil {
+			return fmt.Errorf("failed to decode JSON output: %w", err)
+		}
+		s.Output = jsonOutput
+	default:
+		return fmt.Errorf("unknown function type: %s", kind)
+	}
+	return nil
+}
+
+// MetricType is the type of a metric in the gateway configuration
+type MetricType string
+
+const (
+	MetricTypeBoolean MetricType = "boolean"
+	MetricTypeFloat   MetricType = "float"
+)
+
+// MetricValue is the value of a boolean or float metric. The gateway does not
+// report the type of a metric with its value, and boolean metrics may be
+// reported as the numbers 0 and 1. Pass the metric types with
+// ListInferencesRequest.MetricTypes, or call As, to type the values by their
+// metric; otherwise they are typed by their JSON encoding.
+type MetricValue struct {
+	boolean *bool
+	float   *float64
+}
+
+// BoolMetricValue returns the value of a boolean metric
+func BoolMetricValue(v bool) MetricValue {
+	return MetricValue{boolean: &v}
+}
+
+// FloatMetricValue returns the value of a float metric
+func FloatMetricValue(v float64) MetricValue {
+	return MetricValue{float: &v}
+}
+
+// Bool returns the value of a boolean metric. The numbers 0 and 1 are accepted
+// as false and true; ok is false for other numbers.
+func (m MetricValue) Bool() (value bool, ok bool) {
+	switch {
+	case m.boolean != nil:
+		return *m.boolean, true
+	case m.float != nil && (*m.float == 0 || *m.float == 1):
+		return *m.float == 1, true
+	}
+	return false, false
+}
+
+// Float returns the value of a float metric. ok is false for boolean metrics.
+func (m MetricValue) Float() (value float64, ok bool) {
+	if m.float == nil {
+		return 0, false
+	}
+	return *m.float, true
+}
+
+// As returns the value typed as a value of a metric of type t
+func (m MetricValue) As(t MetricType) (MetricValue, error) {
+	if m.boolean == nil && m.float == nil {
+		return m, nil
+	}
+	switch t {
+	case MetricTypeBoolean:
+		if v, ok := m.Bool(); ok {
+			return BoolMetricValue(v), nil
+		}
+		return m, fmt.Errorf("metric value %s is not a boolean", m)
+	case MetricTypeFloat:
+		if m.boolean != nil {
+			return m, fmt.Errorf("metric value %s is not a number", m)
+		}
+		return m, nil
+	}
+	return m, fmt.Errorf("unknown metric type: %s", t)
+}
+
+// TypeMetricValues types the metric values by the types of their metrics.
+// Metrics missing from types keep the type of their JSON encoding.
+func (s *StoredInference) TypeMetricValues(types map[string]MetricType) error {
+	for name, value := range s.MetricValues {
+		t, ok := types[name]
+		if !ok {
+			continue
+		}
+		typed, err := value.As(t)
+		if err != nil {
+			return fmt.Errorf("metric %s: %w", name, err)
+		}
+		s.MetricValues[name] = typed
+	}
+	return nil
+}
+
+// Value returns the value as bool or float64, or nil if it is not set
+func (m MetricValue) Value() interface{} {
+	switch {
+	case m.boolean != nil:
+		return *m.boolean
+	case m.float != nil:
+		return *m.float
+	}
+	return nil
+}
+
+// String formats the value for logs
+func (m MetricValue) String() string {
+	return fmt.Sprint(m.Value())
+}
+
+// MarshalJSON encodes the value as a JSON boolean or number
+func (m MetricValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Value())
+}
+
+// UnmarshalJSON decodes a JSON boolean or number
+func (m *MetricValue) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*m = BoolMetricValue(v)
+	case float64:
+		*m = FloatMetricValue(v)
+	case nil:
+		*m = MetricValue{}
+	default:
+		return fmt.Errorf("metric value must be a boolean or a number, got %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+//go:build unit
+
+package inference
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoredChatInference(t *testing.T) {
+	data := `{"type":"chat","id":"` + testInferenceID + `","episode_id":"` + testEpisodeID + `","function_name":"f","variant_name":"v1",
+		"input":{"messages":[{"role":"user","content":[{"type":"text","text":"Hi"}]}]},
+		"output":[{"type":"text","text":"Hello"},{"type":"tool_call","id":"1","raw_name":"f","raw_arguments":"{\"q\":\"x\"}","arguments":{"q":"x"}}],
+		"output_source":"demonstration","dispatch_type":"batch","extra_body":[{"variant_name":"v1","pointer":"/temperature","value":0.5}],
+		"timestamp":"2025-01-01T00:00:00Z","metric_values":{"accurate":true,"score":0.75}}`
+
+	var stored StoredInference
+	require.NoError(t, json.Unmarshal([]byte(data), &stored))
+	assert.Equal(t, FunctionTypeChat, stored.Type)
+	assert.Equal(t, InferenceOutputSourceDemonstration, stored.OutputSource)
+	assert.Equal(t, DispatchType("batch"), stored.DispatchType)
+	assert.Equal(t, []ExtraBody{SetExtraBody("/temperature", 0.5).ForVariant("v1")}, stored.ExtraBody)
+
+	content, ok := stored.ChatOutput()
+	require.True(t, ok)
+	require.Len(t, content, 2)
+	assert.Equal(t, shared.NewText("Hello"), content[0])
+	_, ok = stored.JsonOutput()
+	assert.False(t, ok)
+
+	accurate, ok := stored.MetricValues["accurate"].Bool()
+	require.True(t, ok)
+	assert.True(t, accurate)
+	_, ok = stored.MetricValues["accurate"].Float()
+	assert.False(t, ok)
+	score, ok := stored.MetricValues["score"].Float()
+	require.True(t, ok)
+	assert.Equal(t, 0.75, score)
+
+	// The stored inference survives a round trip
+	encoded, err := json.Marshal(stored)
+	require.NoError(t, err)
+	var decoded StoredInference
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, stored, decoded)
+}
+
+func TestStoredJsonInference(t *testing.T) {
+	data := `{"type":"json","id":"` + testInferenceID + `","episode_id":"` + testEpisodeID + `","function_name":"f","variant_name":"v1",
+		"input":{},"output":{"raw":"{\"a\":1}","parsed":{"a":1}},"timestamp":"2025-01-01T00:00:00Z"}`
+
+	var stored StoredInference
+	require.NoError(t, json.Unmarshal([]byte(data), &stored))
+
+	output, ok := stored.JsonOutput()
+	require.True(t, ok)
+	assert.Equal(t, JsonInferenceOutput{Raw: util.StringPtr(`{"a":1}`), Parsed: map[string]interface{}{"a": float64(1)}}, output)
+	_, ok = stored.ChatOutput()
+	assert.False(t, ok)
+}
+
+func TestStoredInferenceOutputWithoutType(t *testing.T) {
+	var chat StoredInference
+	require.NoError(t, json.Unmarshal([]byte(`{"output":[{"type":"text","text":"Hi"}]}`), &chat))
+	_, ok := chat.ChatOutput()
+	assert.True(t, ok)
+
+	var jsonInference StoredInference
+	require.NoError(t, json.Unmarshal([]byte(`{"output":{"raw":"{}"}}`), &jsonInference))
+	_, ok = jsonInference.JsonOutput()
+	assert.True(t, ok)
+
+	var empty StoredInference
+	require.NoError(t, json.Unmarshal([]byte(`{"output":null}`), &empty))
+	assert.Nil(t, empty.Output)
+}
+
+func TestStoredInferenceErrors(t *testing.T) {
+	for _, data := range []string{
+		`{"type":"chat","output":{"raw":"{}"}}`,
+		`{"type":"json","output":[]}`,
+		`{"type":"audio","output":[]}`,
+		`{"output":[{"type":"image"}]}`,
+		`{"metric_values":{"label":"good"}}`,
+	} {
+		var stored StoredInference
+		assert.Error(t, json.Unmarshal([]byte(data), &stored), data)
+	}
+}
+
+func TestMetricValue(t *testing.T) {
+	var zero MetricValue
+	assert.Nil(t, zero.Value())
+
+	data, err := json.Marshal(map[string]MetricValue{"a": BoolMetricValue(false), "b": FloatMetricValue(2.5)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":false,"b":2.5}`, string(data))
+	assert.Equal(t, "2.5", FloatMetricValue(2.5).String())
+
+	// Boolean metrics may be reported as 0 or 1
+	var values map[string]MetricValue
+	require.NoError(t, json.Unmarshal([]byte(`{"stored_as_number":1,"score":0.5}`), &values))
+	value, ok := values["stored_as_number"].Bool()
+	require.True(t, ok)
+	assert.True(t, value)
+	_, ok = values["score"].Bool()
+	assert.False(t, ok)
+}
+
+func TestStoredInferenceTypeMetricValues(t *testing.T) {
+	var stored StoredInference
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"`+testInferenceID+`","output":null,
+		"metric_values":{"accurate":0,"score":1,"other":true}}`), &stored))
+
+	require.NoError(t, stored.TypeMetricValues(map[string]MetricType{"accurate": MetricTypeBoolean, "score": MetricTypeFloat}))
+	assert.Equal(t, map[string]MetricValue{
+		"accurate": BoolMetricValue(false),
+		"score":    FloatMetricValue(1),
+		"other":    BoolMetricValue(true),
+	}, stored.MetricValues)
+
+	assert.Error(t, stored.TypeMetricValues(map[string]MetricType{"other": MetricTypeFloat}))
+	_, err := FloatMetricValue(0.5).As(MetricTypeBoolean)
+	assert.Error(t, err)
+	_, err = FloatMetricValue(1).As("counter")
+	assert.Error(t, err)
+}
@@ -24,6 +24,22 @@ type Gateway interface {
 	Close() error
 }
 
+// Streamer is implemented by gateways that provide pull-based streams. The
+// gateways of this package implement it; use OpenStream to stream from any Gateway.
+// Stream returns the error of a request that fails before streaming starts;
+// errors during the stream are reported by Stream.Err.
+type Streamer interface {
+	Stream(ctx context.Context, req *inference.InferenceRequest) (*Stream, error)
+}
+
+// HealthChecker is implemented by gateways that report the health and version
+// of the TensorZero gateway. The gateways of this package implement it.
+type HealthChecker interface {
+	Health(ctx context.Context) (*HealthResponse, error)
+	Status(ctx context.Context) (*StatusResponse, error)
+	WaitUntilReady(ctx context.Context) (*StatusResponse, error)
+}
+
 // OptimizationJobHandle represents an optimization job handle
 type OptimizationJobHandle interface {
 	GetType() string
@@ -22,6 +22,7 @@ import (
 type MockGateway struct {
 	InferenceFn                   func(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error)
 	InferenceStreamFn             func(ctx context.Context, req *inference.InferenceRequest) (<-chan inference.InferenceChunk, <-chan error)
+	StreamFn                      func(ctx context.Context, req *inference.InferenceRequest) (*Stream, error)
 	FeedbackFn                    func(ctx context.Context, req *feedback.Request) (*feedback.Response, error)
 	DynamicEvaluationRunFn        func(ctx context.Context, req *evaluation.RunRequest) (*evaluation.RunResponse, error)
 	DynamicEvaluationRunEpisodeFn func(ctx context.Context, req *evaluation.EpisodeRequest) (*evaluation.EpisodeResponse, error)
@@ -29,6 +30,9 @@ type MockGateway struct {
 	DeleteDatapointFn             func(ctx context.Context, datasetName string, datapointID uuid.UUID) error
 	ListDatapointsFn              func(ctx context.Context, req *datapoint.ListDatapointsRequest) ([]datapoint.Datapoint, error)
 	ListInferencesFn              func(ctx context.Context, req *inference.ListInferencesRequest) ([]inference.StoredInference, error)
+	HealthFn                      func(ctx context.Context) (*HealthResponse, error)
+	StatusFn                      func(ctx context.Context) (*StatusResponse, error)
+	WaitUntilReadyFn              func(ctx context.Context) (*StatusResponse, error)
 	CloseFn                       func() error
 }
 
@@ -38,6 +42,9 @@ func (m *MockGateway) Inference(ctx context.Context, req *inference.InferenceReq
 func (m *MockGateway) InferenceStream(ctx context.Context, req *inference.InferenceRequest) (<-chan inference.InferenceChunk, <-chan error) {
 	return m.InferenceStreamFn(ctx, req)
 }
+func (m *MockGateway) Stream(ctx context.Context, req *inference.InferenceRequest) (*Stream, error) {
+	return m.StreamFn(ctx, req)
+}
 func (m *MockGateway) DynamicEvaluationRun(ctx context.Context, req *evaluation.RunRequest) (*evaluation.RunResponse, error) {
 	return m.DynamicEvaluationRunFn(ctx, req)
 }
@@ -56,6 +63,15 @@ func (m *MockGateway) ListDatapoints(ctx context.Context, req *datapoint.ListDat
 func (m *MockGateway) ListInferences(ctx context.Context, req *inference.ListInferencesRequest) ([]inference.StoredInference, error) {
 	return m.ListInferencesFn(ctx, req)
 }
+func (m *MockGateway) Health(ctx context.Context) (*HealthResponse, error) {
+	return m.HealthFn(ctx)
+}
+func (m *MockGateway) Status(ctx context.Context) (*StatusResponse, error) {
+	return m.StatusFn(ctx)
+}
+func (m *MockGateway) WaitUntilReady(ctx context.Context) (*StatusResponse, error) {
+	return m.WaitUntilReadyFn(ctx)
+}
 func (m *MockGateway) Close() error {
 	return m.CloseFn()
 }
@@ -0,0 +1,292 @@
+package tensorzero
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/inference"
+)
+
+// redacted replaces sensitive values in log output
+const redacted = "[REDACTED]"
+
+// RedactFunc scrubs sensitive content from text before it is logged
+type RedactFunc func(string) string
+
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	apiKeyPattern = regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_\-]{8,}|(?i:bearer)\s+[A-Za-z0-9._~+/\-]+=*`)
+)
+
+// RedactPatterns returns a RedactFunc that replaces every match of the given patterns
+func RedactPatterns(patterns ...*regexp.Regexp) RedactFunc {
+	return func(s string) string {
+		for _, pattern := range patterns {
+			s = pattern.ReplaceAllString(s, redacted)
+		}
+		return s
+	}
+}
+
+// RedactEmails replaces email addresses
+func RedactEmails(s string) string {
+	return emailPattern.ReplaceAllString(s, redacted)
+}
+
+// RedactAPIKeys replaces API keys such as "sk-..." and bearer tokens
+func RedactAPIKeys(s string) string {
+	return apiKeyPattern.ReplaceAllString(s, redacted)
+}
+
+// sensitiveHeaders are never logged in clear text
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// WithLogger logs every gateway call to logger: endpoint, status, latency,
+// inference ID and variant at info level (errors at error level), and the
+// redacted request and response bodies, headers and gateway error messages
+// at debug level.
+func WithLogger(logger *slog.Logger) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.logger = logger
+	}
+}
+
+// WithLogRedaction replaces the default content redaction (API keys and email
+// addresses) applied to logged bodies and error messages. Provider credentials
+// and authentication headers are always redacted.
+func WithLogRedaction(hooks ...RedactFunc) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.redactors = hooks
+	}
+}
+
+var defaultRedactors = []RedactFunc{RedactAPIKeys, RedactEmails}
+
+// redact applies the configured redaction hooks to s
+func (g *httpGateway) redact(s string) string {
+	redactors := g.redactors
+	if redactors == nil {
+		redactors = defaultRedactors
+	}
+	for _, hook := range redactors {
+		s = hook(s)
+	}
+	return s
+}
+
+// redactBody removes provider credentials at any depth of a JSON body and
+// applies the redaction hooks
+func (g *httpGateway) redactBody(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err == nil && scrubCredentials(value) {
+		var scrubbed bytes.Buffer
+		encoder := json.NewEncoder(&scrubbed)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err == nil {
+			body = bytes.TrimSuffix(scrubbed.Bytes(), []byte("\n"))
+		}
+	}
+	return g.redact(string(body))
+}
+
+// scrubCredentials replaces the values of all "credentials" members within a
+// decoded JSON value and reports whether it found any
+func scrubCredentials(value interface{}) bool {
+	found := false
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, member := range value {
+			if key != "credentials" {
+				found = scrubCredentials(member) || found
+				continue
+			}
+			found = true
+			if credentials, ok := member.(map[string]interface{}); ok {
+				for name := range credentials {
+					credentials[name] = redacted
+				}
+			} else {
+				value[key] = redacted
+			}
+		}
+	case []interface{}:
+		for _, element := range value {
+			found = scrubCredentials(element) || found
+		}
+	}
+	return found
+}
+
+// redactHeaders returns a copy of the headers with authentication values hidden
+func (g *httpGateway) redactHeaders(header http.Header, cred *Credential) http.Header {
+	out := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, redacted)
+		}
+	}
+	if cred != nil {
+		out.Set(cred.header(), redacted)
+	}
+	return out
+}
+
+// logAttempt logs a single HTTP attempt with its redacted headers at debug level
+func (g *httpGateway) logAttempt(ctx context.Context, r *apiRequest, httpReq *http.Request, cred *Credential, resp *http.Response, start time.Time, err error) {
+	if g.logger == nil || !g.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("operation", string(r.op)),
+		slog.String("method", r.method),
+		slog.String("endpoint", r.path),
+		slog.Duration("latency", time.Since(start)),
+		slog.Any("request_headers", g.redactHeaders(httpReq.Header, cred)),
+	}
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", g.redact(err.Error())))
+	}
+	g.logger.LogAttrs(ctx, slog.LevelDebug, "tensorzero http attempt", attrs...)
+}
+
+// responseIDs holds the identifiers logged for inference responses and chunks
+type responseIDs struct {
+	InferenceID string `json:"inference_id"`
+	EpisodeID   string `json:"episode_id"`
+	VariantName string `json:"variant_name"`
+}
+
+// logCall logs the outcome of a gateway call
+func (g *httpGateway) logCall(ctx context.Context, r *apiRequest, start time.Time, status int, ids *responseIDs, respBody []byte, err error, extra ...slog.Attr) {
+	if g.logger == nil {
+		return
+	}
+
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		slog.String("operation", string(r.op)),
+		slog.String("method", r.method),
+		slog.String("endpoint", r.path),
+		slog.Duration("latency", time.Since(start)),
+	}
+	attrs = append(attrs, extra...)
+
+	var tzErr *tzerrors.TensorZeroError
+	if err != nil && errors.As(err, &tzErr) {
+		status = tzErr.StatusCode
+		respBody = []byte(tzErr.Text)
+	}
+	if status > 0 {
+		attrs = append(attrs, slog.Int("status", status))
+	}
+
+	if ids != nil {
+		if ids.InferenceID != "" {
+			attrs = append(attrs, slog.String("inference_id", ids.InferenceID))
+		}
+		if ids.EpisodeID != "" {
+			attrs = append(attrs, slog.String("episode_id", ids.EpisodeID))
+		}
+		if ids.VariantName != "" {
+			attrs = append(attrs, slog.String("variant_name", ids.VariantName))
+		}
+	}
+
+	debug := g.logger.Enabled(ctx, slog.LevelDebug)
+	if err != nil {
+		level = slog.LevelError
+		if tzErr != nil {
+			// Gateway error messages may echo prompt content, so they are
+			// only logged at debug level
+			attrs = append(attrs, slog.String("error_category", string(tzErr.Category)))
+			if tzErr.RequestID != "" {
+				attrs = append(attrs, slog.String("request_id", tzErr.RequestID))
+			}
+			if debug {
+				attrs = append(attrs, slog.String("error", g.redact(tzErr.Message)))
+			}
+		} else {
+			attrs = append(attrs, slog.String("error", g.redact(err.Error())))
+		}
+	}
+
+	if debug {
+		if r.body != nil {
+			attrs = append(attrs, slog.String("request_body", g.redactBody(r.body)))
+		}
+		if len(respBody) > 0 {
+			attrs = append(attrs, slog.String("response_body", g.redactBody(respBody)))
+		}
+	}
+
+	g.logger.LogAttrs(ctx, level, "tensorzero gateway call", attrs...)
+}
+
+// streamIDs collects the identifiers and chunk count of a stream for logging.
+// A stream closed from another goroutine is logged while its reader may
+// still be observing chunks, hence the lock.
+type streamIDs struct {
+	mu     sync.Mutex
+	ids    responseIDs
+	chunks int
+}
+
+func (s *streamIDs) observe(chunk inference.InferenceChunk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chunks++
+	if s.chunks == 1 {
+		s.ids.InferenceID = chunk.GetInferenceID().String()
+		s.ids.EpisodeID = chunk.GetEpisodeID().String()
+		s.ids.VariantName = chunk.GetVariantName()
+	}
+}
+
+// snapshot returns the identifiers of the stream, or nil before the first
+// chunk, and the number of chunks observed so far
+func (s *streamIDs) snapshot() (*responseIDs, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.chunks == 0 {
+		return nil, 0
+	}
+	ids := s.ids
+	return &ids, s.chunks
+}
+
+// logStream logs the outcome of a streaming inference once the stream has ended
+func (g *httpGateway) logStream(ctx context.Context, r *apiRequest, start time.Time, status int, ids *streamIDs, err error) {
+	if g.logger == nil {
+		return
+	}
+	observed, chunks := ids.snapshot()
+	g.logCall(ctx, r, start, status, observed, nil, err, slog.Int("chunks", chunks))
+}
+
+// parseResponseIDs extracts the identifiers of an inference response for logging
+func parseResponseIDs(op Operation, body []byte) *responseIDs {
+	if op != OperationInference {
+		return nil
+	}
+	var ids responseIDs
+	if err := json.Unmarshal(body, &ids); err != nil {
+		return nil
+	}
+	return &ids
+}
@@ -0,0 +1,186 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(level slog.Level) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level})), &buf
+}
+
+func TestLoggerInference(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"inference_id": "550e8400-e29b-41d4-a716-446655440000",
+			"episode_id": "550e8400-e29b-41d4-a716-446655440001",
+			"variant_name": "variant_a",
+			"content": [{"type": "text", "text": "Hello"}]
+		}`))
+	}))
+	defer server.Close()
+
+	logger, buf := newTestLogger(slog.LevelInfo)
+	client := NewHTTPGateway(server.URL, WithLogger(logger))
+	_, err := client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "level=INFO")
+	assert.Contains(t, out, "operation=inference")
+	assert.Contains(t, out, "endpoint=/inference")
+	assert.Contains(t, out, "status=200")
+	assert.Contains(t, out, "latency=")
+	assert.Contains(t, out, "inference_id=550e8400-e29b-41d4-a716-446655440000")
+	assert.Contains(t, out, "variant_name=variant_a")
+	assert.NotContains(t, out, "request_body")
+}
+
+func TestLoggerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Unknown function: chat"}`))
+	}))
+	defer server.Close()
+
+	logger, buf := newTestLogger(slog.LevelInfo)
+	client := NewHTTPGateway(server.URL, WithLogger(logger))
+	_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.Error(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "level=ERROR")
+	assert.Contains(t, out, "status=400")
+	assert.Contains(t, out, "request_id=req-1")
+	assert.Contains(t, out, "error_category=")
+	// Gateway error messages are only logged at debug level
+	assert.NotContains(t, out, "Unknown function")
+}
+
+func TestLoggerErrorMessageAtDebug(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Invalid input for customer-42"}`))
+	}))
+	defer server.Close()
+
+	logger, buf := newTestLogger(slog.LevelDebug)
+	client := NewHTTPGateway(server.URL,
+		WithLogger(logger),
+		WithLogRedaction(RedactPatterns(regexp.MustCompile(`customer-\d+`))),
+	)
+	_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.Error(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "Invalid input for [REDACTED]")
+	assert.NotContains(t, out, "customer-42")
+}
+
+func TestRedactBodyNestedCredentials(t *testing.T) {
+	g := NewHTTPGateway("http://localhost").(*httpGateway)
+	out := g.redactBody([]byte(`{"inferences":[{"credentials":{"openai_key":"secret-1"},"seed":12345678901234567890}],"params":{"credentials":"secret-2"},"text":"<b>"}`))
+
+	assert.NotContains(t, out, "secret-1")
+	assert.NotContains(t, out, "secret-2")
+	assert.Contains(t, out, `"openai_key":"[REDACTED]"`)
+	// Numbers and markup are kept as sent
+	assert.Contains(t, out, "12345678901234567890")
+	assert.Contains(t, out, "<b>")
+}
+
+func TestLoggerDebugRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"inference_id": "550e8400-e29b-41d4-a716-446655440000", "content": [{"type": "text", "text": "mail jane@example.com"}]}`))
+	}))
+	defer server.Close()
+
+	logger, buf := newTestLogger(slog.LevelDebug)
+	client := NewHTTPGateway(server.URL, WithLogger(logger), WithAPIKey("sk-t0-gateway-secret"))
+	_, err := client.Inference(context.Background(), &inference.InferenceRequest{
+		FunctionName: util.StringPtr("chat"),
+		Credentials:  map[string]string{"openai_key": "provider-secret"},
+		Input: inference.InferenceInput{
+			Messages: []shared.Message{{Role: "user", Content: []shared.ContentBlock{
+				shared.NewText("my key is sk-live-abcdefghijk"),
+			}}},
+		},
+	})
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "request_body=")
+	assert.Contains(t, out, "response_body=")
+	assert.Contains(t, out, "tensorzero http attempt")
+	assert.Contains(t, out, "[REDACTED]")
+	for _, secret := range []string{"sk-t0-gateway-secret", "provider-secret", "sk-live-abcdefghijk", "jane@example.com"} {
+		assert.NotContains(t, out, secret)
+	}
+}
+
+func TestWithLogRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(feedbackOK))
+	}))
+	defer server.Close()
+
+	logger, buf := newTestLogger(slog.LevelDebug)
+	client := NewHTTPGateway(server.URL,
+		WithLogger(logger),
+		WithLogRedaction(RedactPatterns(regexp.MustCompile(`customer-\d+`))),
+	)
+	_, err := client.Feedback(context.Background(), &feedback.Request{
+		MetricName: "rating",
+		Value:      "customer-42 wrote to jane@example.com",
+	})
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.NotContains(t, out, "customer-42")
+	// The default hooks are replaced, so email addresses are kept
+	assert.Contains(t, out, "jane@example.com")
+}
+
+func TestLoggerStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"inference_id\":\"550e8400-e29b-41d4-a716-446655440000\",\"episode_id\":\"550e8400-e29b-41d4-a716-446655440001\",\"variant_name\":\"v1\",\"raw\":\"{\"}\n\n"))
+		w.Write([]byte("data: {\"inference_id\":\"550e8400-e29b-41d4-a716-446655440000\",\"episode_id\":\"550e8400-e29b-41d4-a716-446655440001\",\"variant_name\":\"v1\",\"raw\":\"}\"}\n\n"))
+	}))
+	defer server.Close()
+
+	logger, buf := newTestLogger(slog.LevelInfo)
+	client := NewHTTPGateway(server.URL, WithLogger(logger))
+	chunks, errs := client.InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	for range chunks {
+	}
+	require.NoError(t, <-errs)
+
+	out := buf.String()
+	assert.Equal(t, 1, strings.Count(out, "tensorzero gateway call"))
+	assert.Contains(t, out, "operation=inference_stream")
+	assert.Contains(t, out, "chunks=2")
+	assert.Contains(t, out, "variant_name=v1")
+}
+
+func TestRedactAPIKeys(t *testing.T) {
+	assert.Equal(t, "key [REDACTED] and [REDACTED]", RedactAPIKeys("key sk-abcdefghijkl and Bearer abc.def"))
+	assert.Equal(t, "contact [REDACTED]", RedactEmails("contact jane.doe@example.org"))
+}
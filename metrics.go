@@ -0,0 +1,169 @@
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/metrics"
+	"github.com/denkhaus/tensorzero/shared"
+)
+
+// defaultFunctionName is the function used by the gateway for model_name requests
+const defaultFunctionName = "tensorzero::default"
+
+// operationEndpoints maps operations to the gateway endpoint they call
+var operationEndpoints = map[Operation]string{
+	OperationInference:                   "/inference",
+	OperationInferenceStream:             "/inference",
+	OperationFeedback:                    "/feedback",
+	OperationDynamicEvaluationRun:        "/dynamic_evaluation_run",
+	OperationDynamicEvaluationRunEpisode: "/dynamic_evaluation_run_episode",
+	OperationBulkInsertDatapoints:        "/datasets/{dataset_name}/datapoints/bulk",
+	OperationDeleteDatapoint:             "/datasets/{dataset_name}/datapoints/{datapoint_id}",
+	OperationListDatapoints:              "/datasets/{dataset_name}/datapoints",
+	OperationListInferences:              "/inferences/list",
+	OperationHealth:                      "/health",
+	OperationStatus:                      "/status",
+}
+
+// endpointResolver is implemented by gateways that resolve the endpoint of an
+// operation at runtime, see endpointRoutes
+type endpointResolver interface {
+	endpoint(op Operation) string
+}
+
+// gatewayEndpoint returns the path template gw calls for op
+func gatewayEndpoint(gw Gateway, op Operation) string {
+	if resolver, ok := gw.(endpointResolver); ok {
+		return resolver.endpoint(op)
+	}
+	return operationEndpoints[op]
+}
+
+// MetricsInterceptor reports request latency per endpoint, inference latency and
+// token usage per function and variant, error counts per status code and stream
+// durations to sink. A nil sink reports to metrics.Default().
+func MetricsInterceptor(sink metrics.Sink) Interceptor {
+	if sink == nil {
+		sink = metrics.Default()
+	}
+
+	return func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		if call.Operation == OperationClose {
+			return next(ctx, call)
+		}
+
+		start := time.Now()
+		resp, err := next(ctx, call)
+		if err != nil {
+			recordCall(sink, call, start, err)
+			return resp, err
+		}
+
+		functionName := inferenceFunctionName(call.Request)
+		stream, ok := resp.(*InferenceStreamResult)
+		if !ok {
+			recordCall(sink, call, start, nil)
+			if r, ok := resp.(inference.InferenceResponse); ok {
+				labels := metrics.Labels{metrics.LabelFunction: functionName, metrics.LabelVariant: r.GetVariantName()}
+				sink.Observe(metrics.InferenceDuration, labels, time.Since(start).Seconds())
+				recordUsage(sink, labels, r.GetUsage())
+			}
+			return resp, nil
+		}
+
+		var variantName string
+		var usage *shared.Usage
+		return ObserveStream(ctx, stream,
+			func(chunk inference.InferenceChunk) {
+				variantName = chunk.GetVariantName()
+				switch c := chunk.(type) {
+				case *inference.ChatChunk:
+					if c.Usage != nil {
+						usage = c.Usage
+					}
+				case *inference.JsonChunk:
+					if c.Usage != nil {
+						usage = c.Usage
+					}
+				}
+			},
+			func(err error) {
+				recordCall(sink, call, start, err)
+				labels := metrics.Labels{metrics.LabelFunction: functionName, metrics.LabelVariant: variantName}
+				sink.Observe(metrics.StreamDuration, labels, time.Since(start).Seconds())
+				if usage != nil {
+					recordUsage(sink, labels, *usage)
+				}
+			},
+		), nil
+	}
+}
+
+// recordCall reports the latency and, for failed calls, the error of a call
+func recordCall(sink metrics.Sink, call *Call, start time.Time, err error) {
+	op := call.Operation
+	endpoint := call.endpoint
+	if endpoint == "" {
+		endpoint = operationEndpoints[op]
+	}
+
+	status := statusLabel(err)
+	sink.Observe(metrics.RequestDuration, metrics.Labels{
+		metrics.LabelOperation: string(op),
+		metrics.LabelEndpoint:  endpoint,
+		metrics.LabelStatus:    status,
+	}, time.Since(start).Seconds())
+
+	if err != nil {
+		sink.Add(metrics.Errors, metrics.Labels{
+			metrics.LabelOperation: string(op),
+			metrics.LabelStatus:    status,
+		}, 1)
+	}
+}
+
+func recordUsage(sink metrics.Sink, labels metrics.Labels, usage shared.Usage) {
+	input := metrics.Labels{metrics.LabelDirection: metrics.DirectionInput}
+	output := metrics.Labels{metrics.LabelDirection: metrics.DirectionOutput}
+	for k, v := range labels {
+		input[k] = v
+		output[k] = v
+	}
+	sink.Add(metrics.Tokens, input, float64(usage.InputTokens))
+	sink.Add(metrics.Tokens, output, float64(usage.OutputTokens))
+}
+
+// statusLabel describes the outcome of a call: "ok", the HTTP status code of a
+// gateway error, "canceled", "timeout" or "error" for transport failures
+func statusLabel(err error) string {
+	var tzErr *tzerrors.TensorZeroError
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &tzErr) && tzErr.StatusCode > 0:
+		return strconv.Itoa(tzErr.StatusCode)
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, tzerrors.ErrTimeout):
+		return "timeout"
+	default:
+		return "error"
+	}
+}
+
+// inferenceFunctionName returns the function targeted by an inference request
+func inferenceFunctionName(req interface{}) string {
+	r, ok := req.(*inference.InferenceRequest)
+	if !ok || r == nil {
+		return ""
+	}
+	if r.FunctionName != nil {
+		return *r.FunctionName
+	}
+	return defaultFunctionName
+}
@@ -0,0 +1,82 @@
+// Package metrics provides client-side metrics for the TensorZero client.
+// Measurements are reported to a Sink; Registry is an in-memory Sink that can
+// be published through expvar and served in the Prometheus text format.
+package metrics
+
+import (
+	"sort"
+	"strings"
+)
+
+// Metric names reported by the client
+const (
+	// RequestDuration is the latency of gateway calls per operation, endpoint and status
+	RequestDuration = "tensorzero_client_request_duration_seconds"
+
+	// InferenceDuration is the latency of inferences per function and variant
+	InferenceDuration = "tensorzero_client_inference_duration_seconds"
+
+	// StreamDuration is the time from starting a stream to its last chunk per function and variant
+	StreamDuration = "tensorzero_client_stream_duration_seconds"
+
+	// Tokens counts input and output tokens per function and variant
+	Tokens = "tensorzero_client_tokens_total"
+
+	// Errors counts failed gateway calls per operation and status code
+	Errors = "tensorzero_client_errors_total"
+
+	// CircuitTransitions counts circuit breaker state changes per endpoint, function
+	// and new state, and per replica for the replicas of a pool gateway
+	CircuitTransitions = "tensorzero_client_circuit_transitions_total"
+)
+
+// Label names used by the client
+const (
+	LabelOperation  = "operation"
+	LabelEndpoint   = "endpoint"
+	LabelStatus     = "status"
+	LabelFunction   = "function_name"
+	LabelVariant    = "variant_name"
+	LabelDirection  = "direction"
+	LabelState      = "state"
+	LabelReplica    = "replica"
+	DirectionInput  = "input"
+	DirectionOutput = "output"
+)
+
+// Labels are the dimensions of a measurement
+type Labels map[string]string
+
+// String formats the labels in a stable order, for example `a="1",b="2"`
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(key)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(l[key]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// Sink receives measurements from the client
+type Sink interface {
+	// Observe records a value, such as a duration in seconds, in a histogram
+	Observe(name string, labels Labels, value float64)
+
+	// Add increments a counter
+	Add(name string, labels Labels, delta float64)
+}
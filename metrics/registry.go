@@ -0,0 +1,248 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Registry is an in-memory Sink holding counters and histograms
+type Registry struct {
+	buckets []float64
+
+	mu         sync.Mutex
+	counters   map[string]map[string]*counter
+	histograms map[string]map[string]*histogram
+}
+
+type counter struct {
+	labels Labels
+	value  float64
+}
+
+type histogram struct {
+	labels Labels
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewRegistry creates an empty registry using buckets as histogram upper
+// bounds, or DefaultBuckets when none are given
+func NewRegistry(buckets ...float64) *Registry {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Registry{
+		buckets:    buckets,
+		counters:   make(map[string]map[string]*counter),
+		histograms: make(map[string]map[string]*histogram),
+	}
+}
+
+var (
+	defaultRegistry     *Registry
+	defaultRegistryOnce sync.Once
+)
+
+// Default returns the process-wide registry, published through expvar as "tensorzero"
+func Default() *Registry {
+	defaultRegistryOnce.Do(func() {
+		defaultRegistry = NewRegistry()
+		defaultRegistry.PublishExpvar("tensorzero")
+	})
+	return defaultRegistry
+}
+
+// Observe records a value in a histogram
+func (r *Registry) Observe(name string, labels Labels, value float64) {
+	key := labels.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	series, ok := r.histograms[name]
+	if !ok {
+		series = make(map[string]*histogram)
+		r.histograms[name] = series
+	}
+	h, ok := series[key]
+	if !ok {
+		h = &histogram{labels: copyLabels(labels), counts: make([]uint64, len(r.buckets))}
+		series[key] = h
+	}
+
+	for i, bound := range r.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Add increments a counter
+func (r *Registry) Add(name string, labels Labels, delta float64) {
+	key := labels.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	series, ok := r.counters[name]
+	if !ok {
+		series = make(map[string]*counter)
+		r.counters[name] = series
+	}
+	c, ok := series[key]
+	if !ok {
+		c = &counter{labels: copyLabels(labels)}
+		series[key] = c
+	}
+	c.value += delta
+}
+
+// Counter returns the current value of a counter
+func (r *Registry) Counter(name string, labels Labels) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.counters[name][labels.String()]; ok {
+		return c.value
+	}
+	return 0
+}
+
+// HistogramCount returns the number of values observed by a histogram
+func (r *Registry) HistogramCount(name string, labels Labels) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if h, ok := r.histograms[name][labels.String()]; ok {
+		return h.count
+	}
+	return 0
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range sortedKeys(r.counters) {
+		if _, err := fmt.Fprintf(w, "# TYPE %s counter\n", name); err != nil {
+			return err
+		}
+		series := r.counters[name]
+		for _, key := range sortedKeys(series) {
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", name, braces(key), formatFloat(series[key].value)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(r.histograms) {
+		if _, err := fmt.Fprintf(w, "# TYPE %s histogram\n", name); err != nil {
+			return err
+		}
+		series := r.histograms[name]
+		for _, key := range sortedKeys(series) {
+			h := series[key]
+			for i, bound := range r.buckets {
+				if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLE(h.labels, formatFloat(bound)), h.counts[i]); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLE(h.labels, "+Inf"), h.count); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s_sum%s %s\n", name, braces(key), formatFloat(h.sum)); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s_count%s %d\n", name, braces(key), h.count); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Handler serves the metrics in the Prometheus text exposition format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WritePrometheus(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// PublishExpvar exposes the registry through the expvar package under name.
+// Like expvar.Publish, it panics if the name is already in use.
+func (r *Registry) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(r.snapshot))
+}
+
+// snapshot returns the metrics as nested maps for expvar
+func (r *Registry) snapshot() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]interface{})
+	for name, series := range r.counters {
+		values := make(map[string]float64, len(series))
+		for key, c := range series {
+			values[key] = c.value
+		}
+		out[name] = values
+	}
+	for name, series := range r.histograms {
+		values := make(map[string]interface{}, len(series))
+		for key, h := range series {
+			values[key] = map[string]interface{}{"count": h.count, "sum": h.sum}
+		}
+		out[name] = values
+	}
+	return out
+}
+
+func copyLabels(labels Labels) Labels {
+	out := make(Labels, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
+func withLE(labels Labels, le string) string {
+	l := copyLabels(labels)
+	l["le"] = le
+	return braces(l.String())
+}
+
+func braces(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "{" + key + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,79 @@
+//go:build unit
+
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelsString(t *testing.T) {
+	labels := Labels{"b": "2", "a": `quote"d`, "c": "new\nline"}
+	assert.Equal(t, `a="quote\"d",b="2",c="new\nline"`, labels.String())
+	assert.Equal(t, "", Labels{}.String())
+}
+
+func TestRegistryCounters(t *testing.T) {
+	r := NewRegistry()
+	r.Add(Tokens, Labels{LabelDirection: DirectionInput}, 10)
+	r.Add(Tokens, Labels{LabelDirection: DirectionInput}, 5)
+	r.Add(Tokens, Labels{LabelDirection: DirectionOutput}, 3)
+
+	assert.Equal(t, 15.0, r.Counter(Tokens, Labels{LabelDirection: DirectionInput}))
+	assert.Equal(t, 3.0, r.Counter(Tokens, Labels{LabelDirection: DirectionOutput}))
+	assert.Equal(t, 0.0, r.Counter(Errors, nil))
+}
+
+func TestRegistryPrometheus(t *testing.T) {
+	r := NewRegistry(0.1, 1)
+	labels := Labels{LabelOperation: "inference"}
+	r.Observe(RequestDuration, labels, 0.05)
+	r.Observe(RequestDuration, labels, 0.5)
+	r.Observe(RequestDuration, labels, 5)
+	r.Add(Errors, Labels{LabelOperation: "inference", LabelStatus: "500"}, 1)
+
+	assert.Equal(t, uint64(3), r.HistogramCount(RequestDuration, labels))
+
+	var b strings.Builder
+	require.NoError(t, r.WritePrometheus(&b))
+	expected := `# TYPE tensorzero_client_errors_total counter
+tensorzero_client_errors_total{operation="inference",status="500"} 1
+# TYPE tensorzero_client_request_duration_seconds histogram
+tensorzero_client_request_duration_seconds_bucket{le="0.1",operation="inference"} 1
+tensorzero_client_request_duration_seconds_bucket{le="1",operation="inference"} 2
+tensorzero_client_request_duration_seconds_bucket{le="+Inf",operation="inference"} 3
+tensorzero_client_request_duration_seconds_sum{operation="inference"} 5.55
+tensorzero_client_request_duration_seconds_count{operation="inference"} 3
+`
+	assert.Equal(t, expected, b.String())
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Equal(t, expected, rec.Body.String())
+}
+
+func TestRegistryExpvar(t *testing.T) {
+	r := NewRegistry()
+	r.PublishExpvar("tensorzero_test_registry")
+	r.Add(Errors, Labels{LabelStatus: "429"}, 2)
+	r.Observe(StreamDuration, Labels{LabelFunction: "f"}, 1.5)
+
+	var snapshot map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("tensorzero_test_registry").String()), &snapshot))
+	assert.Equal(t, 2.0, snapshot[Errors][`status="429"`])
+	assert.Equal(t, map[string]interface{}{"count": 1.0, "sum": 1.5}, snapshot[StreamDuration][`function_name="f"`])
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	assert.Same(t, Default(), Default())
+	assert.NotNil(t, expvar.Get("tensorzero"))
+}
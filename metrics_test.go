@@ -0,0 +1,96 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/metrics"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsInterceptorInference(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"inference_id": "550e8400-e29b-41d4-a716-446655440000",
+			"episode_id": "550e8400-e29b-41d4-a716-446655440001",
+			"variant_name": "variant_a",
+			"content": [{"type": "text", "text": "Hello"}],
+			"usage": {"input_tokens": 10, "output_tokens": 5}
+		}`))
+	}))
+	defer server.Close()
+
+	registry := metrics.NewRegistry()
+	client := Chain(NewHTTPGateway(server.URL), MetricsInterceptor(registry))
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+		require.NoError(t, err)
+	}
+
+	fv := metrics.Labels{metrics.LabelFunction: "chat", metrics.LabelVariant: "variant_a"}
+	assert.Equal(t, uint64(2), registry.HistogramCount(metrics.InferenceDuration, fv))
+	assert.Equal(t, uint64(2), registry.HistogramCount(metrics.RequestDuration, metrics.Labels{
+		metrics.LabelOperation: string(OperationInference),
+		metrics.LabelEndpoint:  "/inference",
+		metrics.LabelStatus:    "ok",
+	}))
+	assert.Equal(t, 20.0, registry.Counter(metrics.Tokens, metrics.Labels{
+		metrics.LabelFunction: "chat", metrics.LabelVariant: "variant_a", metrics.LabelDirection: metrics.DirectionInput,
+	}))
+	assert.Equal(t, 10.0, registry.Counter(metrics.Tokens, metrics.Labels{
+		metrics.LabelFunction: "chat", metrics.LabelVariant: "variant_a", metrics.LabelDirection: metrics.DirectionOutput,
+	}))
+}
+
+func TestMetricsInterceptorErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	registry := metrics.NewRegistry()
+	client := Chain(NewHTTPGateway(server.URL), MetricsInterceptor(registry))
+
+	_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, registry.Counter(metrics.Errors, metrics.Labels{
+		metrics.LabelOperation: string(OperationFeedback),
+		metrics.LabelStatus:    "503",
+	}))
+}
+
+func TestMetricsInterceptorStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"inference_id\":\"550e8400-e29b-41d4-a716-446655440000\",\"episode_id\":\"550e8400-e29b-41d4-a716-446655440001\",\"variant_name\":\"v1\",\"raw\":\"{\"}\n\n"))
+		w.Write([]byte("data: {\"inference_id\":\"550e8400-e29b-41d4-a716-446655440000\",\"episode_id\":\"550e8400-e29b-41d4-a716-446655440001\",\"variant_name\":\"v1\",\"raw\":\"}\",\"usage\":{\"input_tokens\":7,\"output_tokens\":3}}\n\n"))
+	}))
+	defer server.Close()
+
+	registry := metrics.NewRegistry()
+	client := Chain(NewHTTPGateway(server.URL), MetricsInterceptor(registry))
+
+	chunks, errs := client.InferenceStream(context.Background(), &inference.InferenceRequest{ModelName: util.StringPtr("openai::gpt-4o")})
+	for range chunks {
+	}
+	require.NoError(t, <-errs)
+
+	fv := metrics.Labels{metrics.LabelFunction: defaultFunctionName, metrics.LabelVariant: "v1"}
+	require.Eventually(t, func() bool {
+		return registry.HistogramCount(metrics.StreamDuration, fv) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 7.0, registry.Counter(metrics.Tokens, metrics.Labels{
+		metrics.LabelFunction: defaultFunctionName, metrics.LabelVariant: "v1", metrics.LabelDirection: metrics.DirectionInput,
+	}))
+}
@@ -0,0 +1,20 @@
+package tensorzero
+
+// Operation identifies a Gateway method. It is used to configure behaviour
+// per operation, for example which calls may be retried.
+type Operation string
+
+const (
+	OperationInference                   Operation = "inference"
+	OperationInferenceStream             Operation = "inference_stream"
+	OperationFeedback                    Operation = "feedback"
+	OperationDynamicEvaluationRun        Operation = "dynamic_evaluation_run"
+	OperationDynamicEvaluationRunEpisode Operation = "dynamic_evaluation_run_episode"
+	OperationBulkInsertDatapoints        Operation = "bulk_insert_datapoints"
+	OperationDeleteDatapoint             Operation = "delete_datapoint"
+	OperationListDatapoints              Operation = "list_datapoints"
+	OperationListInferences              Operation = "list_inferences"
+	OperationHealth                      Operation = "health"
+	OperationStatus                      Operation = "status"
+	OperationClose                       Operation = "close"
+)
@@ -0,0 +1,554 @@
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/denkhaus/tensorzero/datapoint"
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/evaluation"
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/google/uuid"
+)
+
+// RoutingStrategy selects the replica that serves a call in a pool gateway
+type RoutingStrategy int
+
+const (
+	// RoutingRoundRobin sends calls to the healthy replicas in turn
+	RoutingRoundRobin RoutingStrategy = iota
+	// RoutingLeastInFlight sends calls to the healthy replica with the fewest calls in flight
+	RoutingLeastInFlight
+	// RoutingStickyByEpisode sends calls of an episode to the replica that served
+	// the episode before and falls back to least-in-flight for new episodes
+	RoutingStickyByEpisode
+)
+
+// poolMember is a single replica of the pool
+type poolMember struct {
+	gateway  Gateway
+	inFlight atomic.Int64
+	healthy  atomic.Bool
+}
+
+// poolGateway implements Gateway on top of several gateway replicas
+type poolGateway struct {
+	members []*poolMember
+	options []HTTPGatewayOption
+
+	strategy            RoutingStrategy
+	healthCheckInterval time.Duration
+	healthCheckTimeout  time.Duration
+	affinityCapacity    int
+	failoverOperations  map[Operation]bool
+
+	next atomic.Uint64
+
+	mu        sync.Mutex
+	affinity  map[uuid.UUID]*poolMember
+	affinityQ []uuid.UUID
+	stop      chan struct{}
+	stopped   sync.WaitGroup
+	closeOnce sync.Once
+}
+
+var (
+	_ Streamer      = (*poolGateway)(nil)
+	_ HealthChecker = (*poolGateway)(nil)
+)
+
+// PoolOption represents configuration options for a pool gateway
+type PoolOption func(*poolGateway)
+
+// WithRoutingStrategy sets how calls are distributed over the replicas. Defaults to RoutingRoundRobin.
+func WithRoutingStrategy(strategy RoutingStrategy) PoolOption {
+	return func(p *poolGateway) {
+		p.strategy = strategy
+	}
+}
+
+// WithHealthCheckInterval sets how often every replica's /health endpoint is
+// polled. Defaults to 10 seconds; zero or less disables active health checks.
+func WithHealthCheckInterval(interval time.Duration) PoolOption {
+	return func(p *poolGateway) {
+		p.healthCheckInterval = interval
+	}
+}
+
+// WithHealthCheckTimeout limits the time of a single health check. Defaults to 5 seconds.
+func WithHealthCheckTimeout(timeout time.Duration) PoolOption {
+	return func(p *poolGateway) {
+		p.healthCheckTimeout = timeout
+	}
+}
+
+// WithFailoverOperations lets calls of the given operations fail over to
+// another replica after transport errors and 502, 503 or 504 responses, as the
+// idempotent operations do by default. Inference is not idempotent: a 502 may
+// mean that all variants failed, and failing over runs the inference again.
+func WithFailoverOperations(ops ...Operation) PoolOption {
+	return func(p *poolGateway) {
+		for _, op := range ops {
+			p.failoverOperations[op] = true
+		}
+	}
+}
+
+// WithEpisodeAffinityCapacity sets how many episodes are remembered for
+// sticky routing. Defaults to 10000.
+func WithEpisodeAffinityCapacity(capacity int) PoolOption {
+	return func(p *poolGateway) {
+		p.affinityCapacity = capacity
+	}
+}
+
+// WithGatewayOptions sets the options of the HTTP gateway created for every
+// replica. A rate limiter or circuit breaker passed here is shared by the
+// replicas; the circuit breaker keeps separate circuits for every replica,
+// see CircuitKey.Replica.
+func WithGatewayOptions(options ...HTTPGatewayOption) PoolOption {
+	return func(p *poolGateway) {
+		p.options = append(p.options, options...)
+	}
+}
+
+// NewPoolGateway creates a gateway that distributes calls over several gateway
+// replicas. Replicas that fail their health check or cannot be connected to are
+// skipped until they are healthy again.
+//
+// A call that could not be sent, because the connection failed or a circuit
+// breaker rejected it, is repeated on another replica. Calls of idempotent
+// operations and of operations enabled with WithFailoverOperations are also
+// repeated after transport errors and 502, 503 or 504 responses. Inference and
+// InferenceStream are not idempotent and are not repeated after such errors
+// unless enabled.
+//
+// The returned gateway implements Streamer and HealthChecker.
+func NewPoolGateway(baseURLs []string, options ...PoolOption) (Gateway, error) {
+	if len(baseURLs) == 0 {
+		return nil, fmt.Errorf("pool gateway requires at least one base URL")
+	}
+
+	p := &poolGateway{
+		healthCheckInterval: 10 * time.Second,
+		healthCheckTimeout:  5 * time.Second,
+		affinityCapacity:    10000,
+		failoverOperations: map[Operation]bool{
+			OperationFeedback:        true,
+			OperationDeleteDatapoint: true,
+			OperationListDatapoints:  true,
+			OperationListInferences:  true,
+			OperationHealth:          true,
+			OperationStatus:          true,
+		},
+		affinity: make(map[uuid.UUID]*poolMember),
+		stop:     make(chan struct{}),
+	}
+	for _, option := range options {
+		option(p)
+	}
+
+	for _, baseURL := range baseURLs {
+		gateway := NewHTTPGateway(baseURL, p.options...)
+		gateway.(*httpGateway).replica = strings.TrimSuffix(baseURL, "/")
+		member := &poolMember{gateway: gateway}
+		member.healthy.Store(true)
+		p.members = append(p.members, member)
+	}
+
+	if p.healthCheckInterval > 0 {
+		p.stopped.Add(1)
+		go p.healthCheckLoop()
+	}
+
+	return p, nil
+}
+
+// healthCheckLoop polls the health endpoint of every replica until the pool is closed
+func (p *poolGateway) healthCheckLoop() {
+	defer p.stopped.Done()
+
+	ticker := time.NewTicker(p.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		p.checkHealth()
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// checkHealth updates the health of every replica
+func (p *poolGateway) checkHealth() {
+	var wg sync.WaitGroup
+	for _, member := range p.members {
+		wg.Add(1)
+		go func(member *poolMember) {
+			defer wg.Done()
+			member.healthy.Store(p.probe(member) == nil)
+		}(member)
+	}
+	wg.Wait()
+}
+
+// probe calls the health endpoint of a replica through its gateway, so that
+// the credentials and transport of the replica are used
+func (p *poolGateway) probe(member *poolMember) error {
+	ctx := context.Background()
+	if p.healthCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.healthCheckTimeout)
+		defer cancel()
+	}
+
+	checker, err := healthChecker(member.gateway)
+	if err != nil {
+		return err
+	}
+	health, err := checker.Health(ctx)
+	if err != nil {
+		return err
+	}
+	if health.Gateway != "ok" {
+		return fmt.Errorf("gateway reported %q", health.Gateway)
+	}
+	return nil
+}
+
+// pick selects the replica for a call, skipping replicas that were already tried
+func (p *poolGateway) pick(episodeID *uuid.UUID, tried map[*poolMember]bool) *poolMember {
+	candidates := make([]*poolMember, 0, len(p.members))
+	for _, member := range p.members {
+		if !tried[member] && member.healthy.Load() {
+			candidates = append(candidates, member)
+		}
+	}
+	if len(candidates) == 0 {
+		// Rather try an unhealthy replica than fail without trying
+		for _, member := range p.members {
+			if !tried[member] {
+				candidates = append(candidates, member)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	switch p.strategy {
+	case RoutingStickyByEpisode:
+		if episodeID != nil {
+			p.mu.Lock()
+			member := p.affinity[*episodeID]
+			p.mu.Unlock()
+			for _, candidate := range candidates {
+				if candidate == member {
+					return member
+				}
+			}
+		}
+		return leastInFlight(candidates)
+	case RoutingLeastInFlight:
+		return leastInFlight(candidates)
+	default:
+		return candidates[int(p.next.Add(1)-1)%len(candidates)]
+	}
+}
+
+func leastInFlight(candidates []*poolMember) *poolMember {
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate.inFlight.Load() < best.inFlight.Load() {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// remember records the replica that served an episode for sticky routing
+func (p *poolGateway) remember(episodeID uuid.UUID, member *poolMember) {
+	if p.strategy != RoutingStickyByEpisode || episodeID == uuid.Nil || p.affinityCapacity <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.affinity[episodeID]; !ok {
+		p.affinityQ = append(p.affinityQ, episodeID)
+		if len(p.affinityQ) > p.affinityCapacity {
+			delete(p.affinity, p.affinityQ[0])
+			p.affinityQ = p.affinityQ[1:]
+		}
+	}
+	p.affinity[episodeID] = member
+}
+
+// failover reports whether a failed call of op may be repeated on another replica
+func (p *poolGateway) failover(ctx context.Context, op Operation, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	// The request was not sent, so repeating it is safe for every operation
+	var openErr *CircuitOpenError
+	if errors.As(err, &openErr) || isConnectError(err) {
+		return true
+	}
+	if !p.failoverOperations[op] {
+		return false
+	}
+
+	var tzErr *tzerrors.TensorZeroError
+	if errors.As(err, &tzErr) {
+		switch tzErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// isConnectError reports whether err means that no connection to the replica
+// could be established, so the request was not sent. Errors returned by the
+// gateway, for example when all variants failed, do not make a replica unhealthy.
+func isConnectError(err error) bool {
+	if errors.Is(err, ErrConnectTimeout) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// poolCall runs call on the selected replica and fails over to the next one
+func poolCall[T any](ctx context.Context, p *poolGateway, op Operation, episodeID *uuid.UUID, call func(Gateway) (T, error)) (T, *poolMember, error) {
+	tried := make(map[*poolMember]bool, len(p.members))
+	for {
+		member := p.pick(episodeID, tried)
+		tried[member] = true
+
+		member.inFlight.Add(1)
+		result, err := call(member.gateway)
+		member.inFlight.Add(-1)
+
+		if err == nil {
+			if episodeID != nil {
+				p.remember(*episodeID, member)
+			}
+			return result, member, nil
+		}
+		if isConnectError(err) {
+			member.healthy.Store(false)
+		}
+		if !p.failover(ctx, op, err) || len(tried) == len(p.members) {
+			return result, member, err
+		}
+	}
+}
+
+// Inference makes an inference request on one of the replicas
+func (p *poolGateway) Inference(ctx context.Context, req *inference.InferenceRequest) (inference.InferenceResponse, error) {
+	if req == nil {
+		return nil, tzerrors.NewValidationError("request", "inference request is required")
+	}
+	resp, member, err := poolCall(ctx, p, OperationInference, req.EpisodeID, func(gw Gateway) (inference.InferenceResponse, error) {
+		return gw.Inference(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.remember(resp.GetEpisodeID(), member)
+	return resp, nil
+}
+
+// InferenceStream makes a streaming inference request on one of the replicas.
+// The call fails over to another replica only until the first chunk was received.
+func (p *poolGateway) InferenceStream(ctx context.Context, req *inference.InferenceRequest) (<-chan inference.InferenceChunk, <-chan error) {
+	chunkCh := make(chan inference.InferenceChunk, 10)
+	errCh := make(chan error, 1)
+	if req == nil {
+		errCh <- tzerrors.NewValidationError("request", "streaming inference request is required")
+		close(chunkCh)
+		close(errCh)
+		return chunkCh, errCh
+	}
+
+	go func() {
+		defer close(chunkCh)
+		defer close(errCh)
+
+		tried := make(map[*poolMember]bool, len(p.members))
+		for {
+			member := p.pick(req.EpisodeID, tried)
+			tried[member] = true
+
+			member.inFlight.Add(1)
+			received, err := p.forwardStream(ctx, member, req, chunkCh)
+			member.inFlight.Add(-1)
+
+			if err == nil {
+				return
+			}
+			if isConnectError(err) {
+				member.healthy.Store(false)
+			}
+			if received > 0 || !p.failover(ctx, OperationInferenceStream, err) || len(tried) == len(p.members) {
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	return chunkCh, errCh
+}
+
+// Stream makes a streaming inference request on one of the replicas. It
+// returns once the first chunk arrived, or with the error of a request that
+// failed on every replica tried.
+func (p *poolGateway) Stream(ctx context.Context, req *inference.InferenceRequest) (*Stream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	chunks, errs := p.InferenceStream(ctx, req)
+	return openChannels(chunks, errs, cancel)
+}
+
+// forwardStream forwards the chunks of a stream from member and returns the number of forwarded chunks
+func (p *poolGateway) forwardStream(ctx context.Context, member *poolMember, req *inference.InferenceRequest, out chan<- inference.InferenceChunk) (int, error) {
+	chunks, errs := member.gateway.InferenceStream(ctx, req)
+	received := 0
+	for chunk := range chunks {
+		if received == 0 {
+			p.remember(chunk.GetEpisodeID(), member)
+		}
+		received++
+
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			// Drain the member stream so that its goroutine can exit
+			for range chunks {
+			}
+			return received, ctx.Err()
+		}
+	}
+	return received, <-errs
+}
+
+// Feedback sends feedback to one of the replicas
+func (p *poolGateway) Feedback(ctx context.Context, req *feedback.Request) (*feedback.Response, error) {
+	resp, _, err := poolCall(ctx, p, OperationFeedback, req.EpisodeID, func(gw Gateway) (*feedback.Response, error) {
+		return gw.Feedback(ctx, req)
+	})
+	return resp, err
+}
+
+// DynamicEvaluationRun starts a dynamic evaluation run on one of the replicas
+func (p *poolGateway) DynamicEvaluationRun(ctx context.Context, req *evaluation.RunRequest) (*evaluation.RunResponse, error) {
+	resp, _, err := poolCall(ctx, p, OperationDynamicEvaluationRun, nil, func(gw Gateway) (*evaluation.RunResponse, error) {
+		return gw.DynamicEvaluationRun(ctx, req)
+	})
+	return resp, err
+}
+
+// DynamicEvaluationRunEpisode starts an episode on one of the replicas
+func (p *poolGateway) DynamicEvaluationRunEpisode(ctx context.Context, req *evaluation.EpisodeRequest) (*evaluation.EpisodeResponse, error) {
+	resp, member, err := poolCall(ctx, p, OperationDynamicEvaluationRunEpisode, nil, func(gw Gateway) (*evaluation.EpisodeResponse, error) {
+		return gw.DynamicEvaluationRunEpisode(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.remember(resp.EpisodeID, member)
+	return resp, nil
+}
+
+// BulkInsertDatapoints inserts datapoints through one of the replicas
+func (p *poolGateway) BulkInsertDatapoints(ctx context.Context, datasetName string, datapoints []datapoint.DatapointInsert) ([]uuid.UUID, error) {
+	ids, _, err := poolCall(ctx, p, OperationBulkInsertDatapoints, nil, func(gw Gateway) ([]uuid.UUID, error) {
+		return gw.BulkInsertDatapoints(ctx, datasetName, datapoints)
+	})
+	return ids, err
+}
+
+// DeleteDatapoint deletes a datapoint through one of the replicas
+func (p *poolGateway) DeleteDatapoint(ctx context.Context, datasetName string, datapointID uuid.UUID) error {
+	_, _, err := poolCall(ctx, p, OperationDeleteDatapoint, nil, func(gw Gateway) (struct{}, error) {
+		return struct{}{}, gw.DeleteDatapoint(ctx, datasetName, datapointID)
+	})
+	return err
+}
+
+// ListDatapoints lists datapoints through one of the replicas
+func (p *poolGateway) ListDatapoints(ctx context.Context, req *datapoint.ListDatapointsRequest) ([]datapoint.Datapoint, error) {
+	datapoints, _, err := poolCall(ctx, p, OperationListDatapoints, nil, func(gw Gateway) ([]datapoint.Datapoint, error) {
+		return gw.ListDatapoints(ctx, req)
+	})
+	return datapoints, err
+}
+
+// ListInferences lists inferences through one of the replicas
+func (p *poolGateway) ListInferences(ctx context.Context, req *inference.ListInferencesRequest) ([]inference.StoredInference, error) {
+	inferences, _, err := poolCall(ctx, p, OperationListInferences, nil, func(gw Gateway) ([]inference.StoredInference, error) {
+		return gw.ListInferences(ctx, req)
+	})
+	return inferences, err
+}
+
+// Health checks whether one of the replicas is serving requests
+func (p *poolGateway) Health(ctx context.Context) (*HealthResponse, error) {
+	resp, _, err := poolCall(ctx, p, OperationHealth, nil, func(gw Gateway) (*HealthResponse, error) {
+		checker, err := healthChecker(gw)
+		if err != nil {
+			return nil, err
+		}
+		return checker.Health(ctx)
+	})
+	return resp, err
+}
+
+// Status returns the status and version of one of the replicas
+func (p *poolGateway) Status(ctx context.Context) (*StatusResponse, error) {
+	resp, _, err := poolCall(ctx, p, OperationStatus, nil, func(gw Gateway) (*StatusResponse, error) {
+		checker, err := healthChecker(gw)
+		if err != nil {
+			return nil, err
+		}
+		return checker.Status(ctx)
+	})
+	return resp, err
+}
+
+// WaitUntilReady polls the replicas until one of them is ready or ctx is done
+func (p *poolGateway) WaitUntilReady(ctx context.Context) (*StatusResponse, error) {
+	return waitUntilReady(ctx, p)
+}
+
+// Close stops the health checks and closes all replicas
+func (p *poolGateway) Close() error {
+	var errs []error
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		p.stopped.Wait()
+		for _, member := range p.members {
+			if err := member.gateway.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
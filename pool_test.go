@@ -0,0 +1,295 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replica is a fake gateway replica counting the calls it served
+type replica struct {
+	*httptest.Server
+	calls   atomic.Int32
+	healthy atomic.Bool
+}
+
+func newReplica(t *testing.T, episodeID string) *replica {
+	rep := &replica{}
+	rep.healthy.Store(true)
+	rep.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !rep.healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/health" {
+			w.Write([]byte(`{"gateway": "ok", "clickhouse": "ok"}`))
+			return
+		}
+		rep.calls.Add(1)
+		switch r.URL.Path {
+		case "/inference":
+			if r.Header.Get("Accept") == "text/event-stream" {
+				w.Write([]byte(`data: {"inference_id":"550e8400-e29b-41d4-a716-446655440000","episode_id":"` + episodeID + `","variant_name":"v1","raw":"{}"}` + "\n\n"))
+				return
+			}
+			w.Write([]byte(`{
+				"inference_id": "550e8400-e29b-41d4-a716-446655440000",
+				"episode_id": "` + episodeID + `",
+				"variant_name": "variant_a",
+				"content": [{"type": "text", "text": "Hello"}]
+			}`))
+		default:
+			w.Write([]byte(feedbackOK))
+		}
+	}))
+	t.Cleanup(rep.Close)
+	return rep
+}
+
+func TestNewPoolGatewayRequiresURLs(t *testing.T) {
+	_, err := NewPoolGateway(nil)
+	assert.Error(t, err)
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	a, b := newReplica(t, uuid.NewString()), newReplica(t, uuid.NewString())
+	pool, err := NewPoolGateway([]string{a.URL, b.URL}, WithHealthCheckInterval(0))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	for i := 0; i < 4; i++ {
+		_, err := pool.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), a.calls.Load())
+	assert.Equal(t, int32(2), b.calls.Load())
+}
+
+func TestPoolFailover(t *testing.T) {
+	a, b := newReplica(t, uuid.NewString()), newReplica(t, uuid.NewString())
+	a.healthy.Store(false)
+	pool, err := NewPoolGateway([]string{a.URL, b.URL}, WithHealthCheckInterval(0))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := pool.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(3), b.calls.Load())
+}
+
+func TestPoolNilInferenceRequest(t *testing.T) {
+	a := newReplica(t, uuid.NewString())
+	pool, err := NewPoolGateway([]string{a.URL}, WithHealthCheckInterval(0))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	var validationErr *tzerrors.ValidationError
+	_, err = pool.Inference(context.Background(), nil)
+	require.ErrorAs(t, err, &validationErr)
+
+	chunks, errs := pool.InferenceStream(context.Background(), nil)
+	for range chunks {
+	}
+	require.ErrorAs(t, <-errs, &validationErr)
+	assert.Equal(t, int32(0), a.calls.Load())
+}
+
+func TestPoolCircuitPerReplica(t *testing.T) {
+	a, b := newReplica(t, uuid.NewString()), newReplica(t, uuid.NewString())
+	a.healthy.Store(false)
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Hour})
+	pool, err := NewPoolGateway([]string{a.URL, b.URL}, WithHealthCheckInterval(0), WithGatewayOptions(WithCircuitBreaker(breaker)))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// The open circuit of the failing replica does not reject calls to the other one
+	for i := 0; i < 3; i++ {
+		_, err := pool.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(3), b.calls.Load())
+	assert.Equal(t, map[CircuitKey]CircuitState{
+		{Replica: a.URL, Endpoint: "/feedback"}: CircuitOpen,
+		{Replica: b.URL, Endpoint: "/feedback"}: CircuitClosed,
+	}, breaker.States())
+}
+
+func TestPoolFailoverOnConnectionError(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	b := newReplica(t, uuid.NewString())
+
+	pool, err := NewPoolGateway([]string{dead.URL, b.URL}, WithHealthCheckInterval(0))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+	require.NoError(t, err)
+	chunks, errs := pool.InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	for range chunks {
+	}
+	require.NoError(t, <-errs)
+	assert.Equal(t, int32(2), b.calls.Load())
+	assert.False(t, pool.(*poolGateway).members[0].healthy.Load())
+}
+
+func TestPoolNoInferenceFailoverOnGatewayError(t *testing.T) {
+	var failed atomic.Int32
+	allVariantsFailed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failed.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`{"error": "All variants failed"}`))
+	}))
+	defer allVariantsFailed.Close()
+	b := newReplica(t, uuid.NewString())
+
+	pool, err := NewPoolGateway([]string{allVariantsFailed.URL, b.URL}, WithHealthCheckInterval(0))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), failed.Load())
+	assert.Equal(t, int32(0), b.calls.Load())
+	assert.True(t, pool.(*poolGateway).members[0].healthy.Load())
+
+	// Feedback is idempotent and fails over, still without marking the replica unhealthy
+	_, err = pool.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), b.calls.Load())
+	assert.True(t, pool.(*poolGateway).members[0].healthy.Load())
+}
+
+func TestPoolInferenceFailoverOptIn(t *testing.T) {
+	a, b := newReplica(t, uuid.NewString()), newReplica(t, uuid.NewString())
+	a.healthy.Store(false)
+	pool, err := NewPoolGateway([]string{a.URL, b.URL},
+		WithHealthCheckInterval(0),
+		WithFailoverOperations(OperationInference),
+	)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), b.calls.Load())
+}
+
+func TestPoolHealthChecksUseGatewayOptions(t *testing.T) {
+	protected := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"gateway": "ok", "clickhouse": "ok"}`))
+	}))
+	defer protected.Close()
+
+	pool, err := NewPoolGateway([]string{protected.URL},
+		WithHealthCheckInterval(0),
+		WithGatewayOptions(WithAPIKey("secret")),
+	)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	p := pool.(*poolGateway)
+	assert.NoError(t, p.probe(p.members[0]))
+}
+
+func TestPoolNoFailoverOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid"}`))
+	}))
+	defer bad.Close()
+
+	pool, err := NewPoolGateway([]string{bad.URL, bad.URL}, WithHealthCheckInterval(0))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestPoolHealthChecks(t *testing.T) {
+	a, b := newReplica(t, uuid.NewString()), newReplica(t, uuid.NewString())
+	a.healthy.Store(false)
+	pool, err := NewPoolGateway([]string{a.URL, b.URL}, WithHealthCheckInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	p := pool.(*poolGateway)
+	require.Eventually(t, func() bool { return !p.members[0].healthy.Load() }, time.Second, 5*time.Millisecond)
+
+	a.healthy.Store(true)
+	require.Eventually(t, func() bool { return p.members[0].healthy.Load() }, time.Second, 5*time.Millisecond)
+}
+
+func TestPoolStickyByEpisode(t *testing.T) {
+	episodeA, episodeB := uuid.NewString(), uuid.NewString()
+	a, b := newReplica(t, episodeA), newReplica(t, episodeB)
+	pool, err := NewPoolGateway([]string{a.URL, b.URL},
+		WithHealthCheckInterval(0),
+		WithRoutingStrategy(RoutingStickyByEpisode),
+	)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// The first inference starts an episode on replica b
+	pool.(*poolGateway).members[0].inFlight.Add(1)
+	resp, err := pool.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+	require.NoError(t, err)
+	pool.(*poolGateway).members[0].inFlight.Add(-1)
+	assert.Equal(t, episodeB, resp.GetEpisodeID().String())
+
+	// Follow-ups of the episode stay on replica b
+	episodeID := resp.GetEpisodeID()
+	for i := 0; i < 3; i++ {
+		_, err := pool.Inference(context.Background(), &inference.InferenceRequest{
+			FunctionName: util.StringPtr("chat"),
+			EpisodeID:    &episodeID,
+		})
+		require.NoError(t, err)
+	}
+	_, err = pool.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0, EpisodeID: &episodeID})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(0), a.calls.Load())
+	assert.Equal(t, int32(5), b.calls.Load())
+}
+
+func TestPoolLeastInFlight(t *testing.T) {
+	a, b := newReplica(t, uuid.NewString()), newReplica(t, uuid.NewString())
+	pool, err := NewPoolGateway([]string{a.URL, b.URL},
+		WithHealthCheckInterval(0),
+		WithRoutingStrategy(RoutingLeastInFlight),
+	)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	pool.(*poolGateway).members[1].inFlight.Add(1)
+	for i := 0; i < 3; i++ {
+		_, err := pool.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(3), a.calls.Load())
+	assert.Equal(t, int32(0), b.calls.Load())
+}
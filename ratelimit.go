@@ -0,0 +1,367 @@
+package tensorzero
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+)
+
+// RateLimit configures the limits applied to a group of calls. Zero values
+// disable a limit. Every attempt of a retried call counts against the request
+// and token budgets; the concurrency slot is held across the attempts.
+type RateLimit struct {
+	// RequestsPerSecond is the sustained rate of requests sent to the gateway.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests that may be sent at once.
+	// Defaults to RequestsPerSecond rounded up.
+	Burst int
+
+	// TokensPerMinute is the budget of estimated tokens for inference requests.
+	TokensPerMinute int
+
+	// MaxConcurrent caps the number of in-flight inferences and streams.
+	MaxConcurrent int
+}
+
+// RateLimitConfig configures a RateLimiter
+type RateLimitConfig struct {
+	// Global limits apply to every call of the gateway.
+	Global RateLimit
+
+	// Functions holds additional limits per function name. Inferences using a
+	// model directly are limited under "tensorzero::default".
+	Functions map[string]RateLimit
+
+	// FailFast returns a *RateLimitError when no capacity is available
+	// instead of waiting for it.
+	FailFast bool
+
+	// EstimateTokens estimates the tokens consumed by an inference from its
+	// JSON request body. Defaults to one token per four bytes.
+	EstimateTokens func(body []byte) int
+}
+
+// LimitKind identifies the limit that rejected a call
+type LimitKind string
+
+const (
+	LimitRequests    LimitKind = "requests"
+	LimitTokens      LimitKind = "tokens"
+	LimitConcurrency LimitKind = "concurrency"
+)
+
+// RateLimitError is returned by a fail-fast RateLimiter when a limit is exhausted.
+// It matches errors.ErrRateLimited.
+type RateLimitError struct {
+	Limit        LimitKind
+	FunctionName string // empty for the global limits
+
+	// RetryAfter is the time until the limit has capacity again; zero for concurrency limits.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	scope := "global"
+	if e.FunctionName != "" {
+		scope = "function " + e.FunctionName
+	}
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("client rate limit exceeded: %s limit of %s (retry after %s)", e.Limit, scope, e.RetryAfter)
+	}
+	return fmt.Sprintf("client rate limit exceeded: %s limit of %s", e.Limit, scope)
+}
+
+// Is makes errors.Is(err, errors.ErrRateLimited) match client-side limits
+func (e *RateLimitError) Is(target error) bool {
+	return target == tzerrors.ErrRateLimited
+}
+
+// RateLimiter limits the requests, estimated tokens and concurrent inferences
+// sent to the gateway. A single limiter may be shared by several gateways.
+type RateLimiter struct {
+	config RateLimitConfig
+	global *limits
+
+	mu        sync.Mutex
+	functions map[string]*limits
+}
+
+// NewRateLimiter creates a RateLimiter from config
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	if config.EstimateTokens == nil {
+		config.EstimateTokens = estimateTokens
+	}
+	return &RateLimiter{
+		config:    config,
+		global:    newLimits(config.Global, ""),
+		functions: make(map[string]*limits),
+	}
+}
+
+// WithRateLimiter limits the calls of the gateway with limiter
+func WithRateLimiter(limiter *RateLimiter) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.rateLimiter = limiter
+	}
+}
+
+// limit waits for capacity for the request if a rate limiter is configured.
+// The returned release function must be called once the call has finished.
+func (g *httpGateway) limit(ctx context.Context, r *apiRequest) (func(), error) {
+	if g.rateLimiter == nil {
+		return func() {}, nil
+	}
+	return g.rateLimiter.acquire(ctx, r)
+}
+
+// limitRetry waits for, or in fail-fast mode checks, request and token budget
+// for another attempt of a request admitted by limit
+func (g *httpGateway) limitRetry(ctx context.Context, r *apiRequest) error {
+	if g.rateLimiter == nil {
+		return nil
+	}
+	return g.rateLimiter.acquireAttempt(ctx, r)
+}
+
+// estimateTokens approximates the token count of a request body
+func estimateTokens(body []byte) int {
+	return len(body)/4 + 1
+}
+
+// limitsFor returns the limits of a function, or nil if it has none
+func (l *RateLimiter) limitsFor(function string) *limits {
+	if function == "" {
+		return nil
+	}
+	cfg, ok := l.config.Functions[function]
+	if !ok {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fl, ok := l.functions[function]
+	if !ok {
+		fl = newLimits(cfg, function)
+		l.functions[function] = fl
+	}
+	return fl
+}
+
+// acquire waits for, or in fail-fast mode checks, capacity for the request.
+// The returned release function must be called once the call has finished.
+// If a limit is exhausted, the budget already taken from the others is refunded.
+func (l *RateLimiter) acquire(ctx context.Context, r *apiRequest) (func(), error) {
+	isInference := r.op == OperationInference || r.op == OperationInferenceStream
+	tokens := 0
+	if isInference {
+		tokens = l.config.EstimateTokens(r.body)
+	}
+
+	var refunds, releases []func()
+	release := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+	fail := func(err error) (func(), error) {
+		release()
+		for _, refund := range refunds {
+			refund()
+		}
+		return nil, err
+	}
+
+	for _, lim := range []*limits{l.global, l.limitsFor(r.function)} {
+		if lim == nil {
+			continue
+		}
+		refund, err := lim.take(ctx, tokens, l.config.FailFast)
+		if err != nil {
+			return fail(err)
+		}
+		refunds = append(refunds, refund)
+		if isInference {
+			done, err := lim.enter(ctx, l.config.FailFast)
+			if err != nil {
+				return fail(err)
+			}
+			releases = append(releases, done)
+		}
+	}
+
+	return release, nil
+}
+
+// acquireAttempt waits for, or in fail-fast mode checks, the request and token
+// budget of a repeated attempt. The concurrency slots of the call are kept.
+func (l *RateLimiter) acquireAttempt(ctx context.Context, r *apiRequest) error {
+	tokens := 0
+	if r.op == OperationInference || r.op == OperationInferenceStream {
+		tokens = l.config.EstimateTokens(r.body)
+	}
+
+	var refunds []func()
+	for _, lim := range []*limits{l.global, l.limitsFor(r.function)} {
+		if lim == nil {
+			continue
+		}
+		refund, err := lim.take(ctx, tokens, l.config.FailFast)
+		if err != nil {
+			for _, refund := range refunds {
+				refund()
+			}
+			return err
+		}
+		refunds = append(refunds, refund)
+	}
+	return nil
+}
+
+// limits holds the buckets and the concurrency semaphore of one scope
+type limits struct {
+	function string
+	requests *tokenBucket
+	tokens   *tokenBucket
+	inFlight chan struct{}
+}
+
+func newLimits(cfg RateLimit, function string) *limits {
+	l := &limits{function: function}
+	if cfg.RequestsPerSecond > 0 {
+		burst := float64(cfg.Burst)
+		if burst <= 0 {
+			burst = math.Ceil(cfg.RequestsPerSecond)
+		}
+		l.requests = newTokenBucket(cfg.RequestsPerSecond, burst)
+	}
+	if cfg.TokensPerMinute > 0 {
+		l.tokens = newTokenBucket(float64(cfg.TokensPerMinute)/60, float64(cfg.TokensPerMinute))
+	}
+	if cfg.MaxConcurrent > 0 {
+		l.inFlight = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return l
+}
+
+// take consumes one request and the estimated tokens from the buckets and
+// returns the function giving them back
+func (l *limits) take(ctx context.Context, tokens int, failFast bool) (func(), error) {
+	refund := func() {}
+	if l.requests != nil {
+		if err := l.requests.take(ctx, 1, failFast); err != nil {
+			return nil, l.limitError(LimitRequests, err)
+		}
+		refund = func() { l.requests.refund(1) }
+	}
+	if l.tokens != nil && tokens > 0 {
+		if err := l.tokens.take(ctx, float64(tokens), failFast); err != nil {
+			refund()
+			return nil, l.limitError(LimitTokens, err)
+		}
+		refundRequest := refund
+		refund = func() {
+			refundRequest()
+			l.tokens.refund(float64(tokens))
+		}
+	}
+	return refund, nil
+}
+
+// enter occupies a concurrency slot and returns the function releasing it
+func (l *limits) enter(ctx context.Context, failFast bool) (func(), error) {
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+
+	leave := func() { <-l.inFlight }
+	if failFast {
+		select {
+		case l.inFlight <- struct{}{}:
+			return leave, nil
+		default:
+			return nil, &RateLimitError{Limit: LimitConcurrency, FunctionName: l.function}
+		}
+	}
+
+	select {
+	case l.inFlight <- struct{}{}:
+		return leave, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *limits) limitError(kind LimitKind, err error) error {
+	if wait, ok := err.(bucketEmpty); ok {
+		return &RateLimitError{Limit: kind, FunctionName: l.function, RetryAfter: time.Duration(wait)}
+	}
+	return err
+}
+
+// bucketEmpty is returned by a fail-fast take with the time until the bucket has capacity
+type bucketEmpty time.Duration
+
+func (e bucketEmpty) Error() string {
+	return fmt.Sprintf("bucket empty for %s", time.Duration(e))
+}
+
+// tokenBucket refills at rate tokens per second up to burst tokens
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// reserve takes n tokens if available, or returns how long to wait for them
+func (b *tokenBucket) reserve(n float64, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	// A request larger than the bucket can only ever wait for a full bucket
+	n = math.Min(n, b.burst)
+	if b.tokens >= n {
+		b.tokens -= n
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// refund gives back n tokens taken for a call that was not sent
+func (b *tokenBucket) refund(n float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.burst, b.tokens+math.Min(n, b.burst))
+}
+
+// take removes n tokens, waiting for them unless failFast is set
+func (b *tokenBucket) take(ctx context.Context, n float64, failFast bool) error {
+	for {
+		wait := b.reserve(n, time.Now())
+		if wait == 0 {
+			return nil
+		}
+		if failFast {
+			return bucketEmpty(wait)
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,228 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const inferenceOK = `{
+	"inference_id": "550e8400-e29b-41d4-a716-446655440000",
+	"episode_id": "550e8400-e29b-41d4-a716-446655440001",
+	"variant_name": "variant_a",
+	"content": [{"type": "text", "text": "Hello"}]
+}`
+
+func TestRateLimiterFailFastRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(feedbackOK))
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(RateLimitConfig{
+		Global:   RateLimit{RequestsPerSecond: 1, Burst: 2},
+		FailFast: true,
+	})
+	client := NewHTTPGateway(server.URL, WithRateLimiter(limiter))
+	req := &feedback.Request{MetricName: "rating", Value: 1.0}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Feedback(context.Background(), req)
+		require.NoError(t, err)
+	}
+
+	_, err := client.Feedback(context.Background(), req)
+	var rateErr *RateLimitError
+	require.ErrorAs(t, err, &rateErr)
+	assert.Equal(t, LimitRequests, rateErr.Limit)
+	assert.Empty(t, rateErr.FunctionName)
+	assert.Greater(t, rateErr.RetryAfter, time.Duration(0))
+	assert.True(t, errors.Is(err, tzerrors.ErrRateLimited))
+}
+
+func TestRateLimiterBlocksUntilCapacity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(feedbackOK))
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(RateLimitConfig{Global: RateLimit{RequestsPerSecond: 20, Burst: 1}})
+	client := NewHTTPGateway(server.URL, WithRateLimiter(limiter))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.Feedback(ctx, &feedback.Request{MetricName: "rating", Value: 1.0})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRateLimiterPerFunctionTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(inferenceOK))
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(RateLimitConfig{
+		Functions: map[string]RateLimit{"expensive": {TokensPerMinute: 100}},
+		FailFast:  true,
+		EstimateTokens: func(body []byte) int {
+			return 60
+		},
+	})
+	client := NewHTTPGateway(server.URL, WithRateLimiter(limiter))
+
+	_, err := client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("expensive")})
+	require.NoError(t, err)
+
+	_, err = client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("expensive")})
+	var rateErr *RateLimitError
+	require.ErrorAs(t, err, &rateErr)
+	assert.Equal(t, LimitTokens, rateErr.Limit)
+	assert.Equal(t, "expensive", rateErr.FunctionName)
+
+	// Other functions are only subject to the global limits
+	_, err = client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("cheap")})
+	require.NoError(t, err)
+}
+
+func TestRateLimiterConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(inferenceOK))
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(RateLimitConfig{Global: RateLimit{MaxConcurrent: 2}})
+	client := NewHTTPGateway(server.URL, WithRateLimiter(limiter))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("chat")})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+}
+
+func TestRateLimiterConcurrencyStream(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"inference_id\":\"550e8400-e29b-41d4-a716-446655440000\",\"episode_id\":\"550e8400-e29b-41d4-a716-446655440001\",\"variant_name\":\"v1\",\"raw\":\"{\"}\n\n"))
+		w.(http.Flusher).Flush()
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	limiter := NewRateLimiter(RateLimitConfig{Global: RateLimit{MaxConcurrent: 1}, FailFast: true})
+	client := NewHTTPGateway(server.URL, WithRateLimiter(limiter))
+	req := &inference.InferenceRequest{FunctionName: util.StringPtr("json")}
+
+	chunks, _ := client.InferenceStream(context.Background(), req)
+	<-chunks
+
+	// The open stream holds the only slot
+	_, err := client.Inference(context.Background(), req)
+	var rateErr *RateLimitError
+	require.ErrorAs(t, err, &rateErr)
+	assert.Equal(t, LimitConcurrency, rateErr.Limit)
+}
+
+func TestTokenBucketLargeRequest(t *testing.T) {
+	bucket := newTokenBucket(10, 5)
+	now := time.Now()
+	// Requests larger than the bucket consume the full bucket instead of waiting forever
+	assert.Equal(t, time.Duration(0), bucket.reserve(50, now))
+	assert.Equal(t, 100*time.Millisecond, bucket.reserve(1, now))
+}
+
+func TestRateLimiterRefundsWhenConcurrencyWaitFails(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(streamEvent))
+		w.(http.Flusher).Flush()
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	// The bucket does not refill within the test
+	limiter := NewRateLimiter(RateLimitConfig{Global: RateLimit{RequestsPerSecond: 0.01, Burst: 2, MaxConcurrent: 1}})
+	client := NewHTTPGateway(server.URL, WithRateLimiter(limiter))
+	req := &inference.InferenceRequest{FunctionName: util.StringPtr("json")}
+
+	stream, err := OpenStream(context.Background(), client, req)
+	require.NoError(t, err)
+
+	// The call gives up waiting for the slot held by the stream
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = OpenStream(ctx, client, req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Its request was refunded, so the second request of the burst is left
+	stream.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	stream, err = OpenStream(ctx, client, req)
+	require.NoError(t, err)
+	stream.Close()
+}
+
+func TestRateLimiterRetriesCountAsRequests(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(RateLimitConfig{
+		Global:   RateLimit{RequestsPerSecond: 0.01, Burst: 2},
+		FailFast: true,
+	})
+	client := NewHTTPGateway(server.URL, WithRateLimiter(limiter), WithRetryPolicy(fastRetryPolicy()))
+
+	// The third attempt exceeds the burst of two requests
+	_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	var rateErr *RateLimitError
+	require.ErrorAs(t, err, &rateErr)
+	assert.Equal(t, LimitRequests, rateErr.Limit)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
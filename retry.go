@@ -0,0 +1,165 @@
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+)
+
+// RetryPolicy configures how failed gateway calls are retried.
+// Only 5xx responses, 429 responses and transport errors are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+
+	// Multiplier is applied to the backoff after every attempt.
+	Multiplier float64
+
+	// Jitter randomizes each backoff by up to this fraction (0 to 1) in either direction.
+	Jitter float64
+
+	// MaxElapsed is the overall deadline for all attempts of a single call.
+	// Zero means the call is only bounded by its context.
+	MaxElapsed time.Duration
+
+	// RespectRetryAfter makes the client wait for the duration given in a
+	// Retry-After response header instead of the computed backoff.
+	RespectRetryAfter bool
+
+	// Operations lists the operations that may be retried. Operations that
+	// are not listed are attempted exactly once.
+	Operations map[Operation]bool
+}
+
+// DefaultRetryPolicy returns a policy with exponential backoff that retries
+// feedback and datapoint deletion. Inference is not retried by default because
+// it is not idempotent; use WithOperations to opt in.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       3,
+		InitialBackoff:    200 * time.Millisecond,
+		MaxBackoff:        5 * time.Second,
+		Multiplier:        2,
+		Jitter:            0.2,
+		MaxElapsed:        30 * time.Second,
+		RespectRetryAfter: true,
+		Operations: map[Operation]bool{
+			OperationFeedback:        true,
+			OperationDeleteDatapoint: true,
+		},
+	}
+}
+
+// WithOperations returns a copy of the policy that also retries the given operations
+func (p RetryPolicy) WithOperations(ops ...Operation) RetryPolicy {
+	return p.setOperations(true, ops)
+}
+
+// WithoutOperations returns a copy of the policy that no longer retries the given operations
+func (p RetryPolicy) WithoutOperations(ops ...Operation) RetryPolicy {
+	return p.setOperations(false, ops)
+}
+
+func (p RetryPolicy) setOperations(enabled bool, ops []Operation) RetryPolicy {
+	operations := make(map[Operation]bool, len(p.Operations)+len(ops))
+	for op, v := range p.Operations {
+		operations[op] = v
+	}
+	for _, op := range ops {
+		operations[op] = enabled
+	}
+	p.Operations = operations
+	return p
+}
+
+// WithRetryPolicy enables retries for failed requests
+func WithRetryPolicy(policy RetryPolicy) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.retryPolicy = &policy
+	}
+}
+
+// allows reports whether calls of the given operation may be retried
+func (p *RetryPolicy) allows(op Operation) bool {
+	return p != nil && p.MaxAttempts > 1 && p.Operations[op]
+}
+
+// backoff returns the delay before the given retry (1 for the first retry)
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		if p.Multiplier > 1 {
+			delay *= p.Multiplier
+		}
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// isRetryableError reports whether a failed attempt may be repeated
+func isRetryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var tzErr *tzerrors.TensorZeroError
+	if errors.As(err, &tzErr) {
+		return tzerrors.IsRetryable(tzErr)
+	}
+	// Transport errors (connection refused, reset, timeout) are reported as *url.Error
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
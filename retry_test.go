@@ -0,0 +1,216 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fastRetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(3))
+	assert.Equal(t, time.Second, policy.backoff(10))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := policy.backoff(1)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 150*time.Millisecond)
+	}
+}
+
+func TestRetryPolicyOperations(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	assert.True(t, policy.allows(OperationFeedback))
+	assert.True(t, policy.allows(OperationDeleteDatapoint))
+	assert.False(t, policy.allows(OperationInference))
+
+	withInference := policy.WithOperations(OperationInference)
+	assert.True(t, withInference.allows(OperationInference))
+	assert.False(t, policy.allows(OperationInference), "original policy must not be modified")
+
+	withoutFeedback := policy.WithoutOperations(OperationFeedback)
+	assert.False(t, withoutFeedback.allows(OperationFeedback))
+
+	var nilPolicy *RetryPolicy
+	assert.False(t, nilPolicy.allows(OperationFeedback))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-1", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+	assert.Equal(t, 10*time.Second, parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now))
+}
+
+func TestRetryFeedbackOnServerError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"feedback_id": "550e8400-e29b-41d4-a716-446655440000"}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL, WithRetryPolicy(fastRetryPolicy()))
+	resp, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.NoError(t, err)
+	assert.Equal(t, uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"), resp.FeedbackID)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL, WithRetryPolicy(fastRetryPolicy()))
+	err := client.DeleteDatapoint(context.Background(), "dataset", uuid.New())
+	require.Error(t, err)
+
+	var tzErr *shared.TensorZeroError
+	require.ErrorAs(t, err, &tzErr)
+	assert.Equal(t, http.StatusBadGateway, tzErr.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetrySkipsClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL, WithRetryPolicy(fastRetryPolicy()))
+	_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryInferenceRequiresOptIn(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{
+			"inference_id": "550e8400-e29b-41d4-a716-446655440000",
+			"episode_id": "550e8400-e29b-41d4-a716-446655440001",
+			"variant_name": "test_variant",
+			"content": [{"type": "text", "text": "Hello"}],
+			"usage": {"input_tokens": 1, "output_tokens": 1}
+		}`))
+	}))
+	defer server.Close()
+
+	req := &inference.InferenceRequest{FunctionName: util.StringPtr("test_function")}
+
+	client := NewHTTPGateway(server.URL, WithRetryPolicy(fastRetryPolicy()))
+	_, err := client.Inference(context.Background(), req)
+	require.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	client = NewHTTPGateway(server.URL, WithRetryPolicy(fastRetryPolicy().WithOperations(OperationInference)))
+	resp, err := client.Inference(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "test_variant", resp.GetVariantName())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	var calls int32
+	var first time.Time
+	var second time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		second = time.Now()
+		w.Write([]byte(`{"feedback_id": "550e8400-e29b-41d4-a716-446655440000"}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL, WithRetryPolicy(fastRetryPolicy()))
+	_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, second.Sub(first), 900*time.Millisecond)
+}
+
+func TestRetryRespectsMaxElapsed(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := fastRetryPolicy()
+	policy.MaxElapsed = time.Second
+	client := NewHTTPGateway(server.URL, WithRetryPolicy(policy))
+
+	start := time.Now()
+	_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryStopsOnContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := fastRetryPolicy()
+	policy.MaxAttempts = 100
+	policy.InitialBackoff = time.Second
+	policy.MaxBackoff = time.Second
+	client := NewHTTPGateway(server.URL, WithRetryPolicy(policy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Feedback(ctx, &feedback.Request{MetricName: "rating", Value: 1.0})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
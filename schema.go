@@ -0,0 +1,218 @@
+package tensorzero
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OutputSchemaFor derives the JSON Schema of a JSON function output from T,
+// following the rules of encoding/json. Struct fields are required unless they
+// are tagged omitempty or omitzero. Pointer fields without these options are
+// required and may be null, as strict JSON modes of providers expect, and
+// structs do not allow additional properties. T must encode as a JSON object.
+func OutputSchemaFor[T any]() (map[string]interface{}, error) {
+	schema, err := newSchemaBuilder().schema(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	if schema["type"] != "object" {
+		return nil, fmt.Errorf("output schema: %s does not encode as a JSON object", reflect.TypeFor[T]())
+	}
+	return schema, nil
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// schemaBuilder derives JSON Schemas from Go types
+type schemaBuilder struct {
+	// visiting holds the struct types being derived, to detect recursive types
+	visiting map[reflect.Type]bool
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{visiting: make(map[reflect.Type]bool)}
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// The encoding is up to the type
+		return map[string]interface{}{}, nil
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]interface{}{"type": "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes byte slices as base64 strings
+			return map[string]interface{}{"type": "string"}, nil
+		}
+		items, err := b.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshalerType) {
+				return nil, fmt.Errorf("output schema: unsupported map key type %s", t.Key())
+			}
+		}
+		values, err := b.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return b.structSchema(t)
+	}
+	return nil, fmt.Errorf("output schema: unsupported type %s", t)
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) (map[string]interface{}, error) {
+	if b.visiting[t] {
+		return nil, fmt.Errorf("output schema: recursive type %s is not supported", t)
+	}
+	b.visiting[t] = true
+	defer delete(b.visiting, t)
+
+	properties := make(map[string]interface{})
+	required := []string{}
+	if err := b.addFields(t, properties, &required); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
+
+// addFields adds the fields of t to properties. Fields of embedded structs
+// without a JSON name are promoted; fields of the outer struct take precedence.
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) error {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema, err := b.schema(field.Type)
+		if err != nil {
+			return fmt.Errorf("%w (field %s.%s)", err, t, field.Name)
+		}
+		if hasTagOption(opts, "string") {
+			schema = map[string]interface{}{"type": "string"}
+		}
+
+		optional := hasTagOption(opts, "omitempty") || hasTagOption(opts, "omitzero")
+		if !optional {
+			if field.Type.Kind() == reflect.Pointer {
+				// nil pointers are encoded as null
+				schema = nullable(schema)
+			}
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+
+	for _, et := range embedded {
+		if b.visiting[et] {
+			return fmt.Errorf("output schema: recursive type %s is not supported", et)
+		}
+		b.visiting[et] = true
+		promoted := make(map[string]interface{})
+		var promotedRequired []string
+		err := b.addFields(et, promoted, &promotedRequired)
+		delete(b.visiting, et)
+		if err != nil {
+			return err
+		}
+
+		var added []string
+		for name, schema := range promoted {
+			if _, ok := properties[name]; !ok {
+				properties[name] = schema
+				added = append(added, name)
+			}
+		}
+		for _, name := range promotedRequired {
+			if slices.Contains(added, name) {
+				*required = append(*required, name)
+			}
+		}
+	}
+	return nil
+}
+
+// nullable allows null in addition to the values of schema. Schemas without
+// a type allow any value already.
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if t, ok := schema["type"].(string); ok {
+		schema["type"] = []string{t, "null"}
+	}
+	return schema
+}
+
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
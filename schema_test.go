@@ -0,0 +1,104 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaBase struct {
+	ID      uuid.UUID `json:"id"`
+	Comment string    `json:"comment"`
+}
+
+type schemaEntity struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score,omitempty"`
+}
+
+type schemaOutput struct {
+	schemaBase
+	Comment   *string        `json:"comment"`
+	Entities  []schemaEntity `json:"entities"`
+	Counts    map[string]int `json:"counts,omitempty"`
+	Valid     bool           `json:"valid"`
+	Count     int64          `json:"count,string"`
+	CreatedAt time.Time      `json:"created_at"`
+	Extra     interface{}    `json:"extra,omitzero"`
+	Ignored   string         `json:"-"`
+	Untagged  []byte
+	internal  string
+	Labels    map[uuid.UUID]int `json:"labels,omitempty"`
+	Best      *schemaEntity     `json:"best"`
+	Note      *string           `json:"note,omitempty"`
+}
+
+func TestOutputSchemaFor(t *testing.T) {
+	schema, err := OutputSchemaFor[schemaOutput]()
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":      map[string]interface{}{"type": "string"},
+			"comment": map[string]interface{}{"type": []string{"string", "null"}},
+			"entities": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name":  map[string]interface{}{"type": "string"},
+						"score": map[string]interface{}{"type": "number"},
+					},
+					"required":             []string{"name"},
+					"additionalProperties": false,
+				},
+			},
+			"counts":     map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "integer"}},
+			"valid":      map[string]interface{}{"type": "boolean"},
+			"count":      map[string]interface{}{"type": "string"},
+			"created_at": map[string]interface{}{"type": "string", "format": "date-time"},
+			"extra":      map[string]interface{}{},
+			"Untagged":   map[string]interface{}{"type": "string"},
+			"labels":     map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "integer"}},
+			"best": map[string]interface{}{
+				"type": []string{"object", "null"},
+				"properties": map[string]interface{}{
+					"name":  map[string]interface{}{"type": "string"},
+					"score": map[string]interface{}{"type": "number"},
+				},
+				"required":             []string{"name"},
+				"additionalProperties": false,
+			},
+			"note": map[string]interface{}{"type": "string"},
+		},
+		"required":             []string{"comment", "entities", "valid", "count", "created_at", "Untagged", "best", "id"},
+		"additionalProperties": false,
+	}, schema)
+}
+
+type schemaNode struct {
+	Children []schemaNode `json:"children"`
+}
+
+func TestOutputSchemaForErrors(t *testing.T) {
+	_, err := OutputSchemaFor[schemaNode]()
+	assert.ErrorContains(t, err, "recursive type")
+
+	_, err = OutputSchemaFor[[]string]()
+	assert.ErrorContains(t, err, "does not encode as a JSON object")
+
+	_, err = OutputSchemaFor[struct {
+		Callback func() `json:"callback"`
+	}]()
+	assert.ErrorContains(t, err, "unsupported type")
+
+	schema, err := OutputSchemaFor[map[string]string]()
+	require.NoError(t, err)
+	assert.Equal(t, "object", schema["type"])
+}
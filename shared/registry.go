@@ -0,0 +1,288 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/denkhaus/tensorzero/tool"
+)
+
+// ContentBlockDecoder decodes the JSON object of a content block
+type ContentBlockDecoder func(data []byte) (ContentBlock, error)
+
+// contentBlockRegistry maps type tags to the decoders of their content blocks.
+// It is used for message content, inference responses, stored inferences and
+// datapoints alike.
+var contentBlockRegistry = struct {
+	sync.RWMutex
+	decoders map[string]ContentBlockDecoder
+}{
+	decoders: map[string]ContentBlockDecoder{
+		"text":        decodeText,
+		"raw_text":    decodeRawText,
+		"image":       decodeImage,
+		"file":        decodeFile,
+		"tool_call":   decodeToolCall,
+		"tool_result": decodeToolResult,
+		"thought":     decodeThought,
+		"unknown":     decodeUnknown,
+		"tool_code":   decodeAs[tool.ToolCodeContent],
+		"tool_output": decodeAs[tool.ToolOutputContent],
+
+		// Tags of image and file blocks used by earlier gateway versions
+		"image_url":    decodeImage,
+		"image_base64": decodeImage,
+		"file_url":     decodeFile,
+		"file_base64":  decodeFile,
+	},
+}
+
+// RegisterContentBlock registers the decoder for content blocks with the given
+// type tag, so that application specific blocks can be decoded. It panics if
+// the tag is empty, decode is nil or the tag is already registered.
+func RegisterContentBlock(typeTag string, decode ContentBlockDecoder) {
+	if typeTag == "" || decode == nil {
+		panic("shared: RegisterContentBlock requires a type tag and a decoder")
+	}
+
+	contentBlockRegistry.Lock()
+	defer contentBlockRegistry.Unlock()
+
+	if _, ok := contentBlockRegistry.decoders[typeTag]; ok {
+		panic("shared: content block type " + typeTag + " is already registered")
+	}
+	contentBlockRegistry.decoders[typeTag] = decode
+}
+
+// RegisterContentBlockType registers a content block type that is decoded with
+// json.Unmarshal into a new T:
+//
+//	shared.RegisterContentBlockType[Citation]("citation")
+func RegisterContentBlockType[T any, P interface {
+	*T
+	ContentBlock
+}](typeTag string) {
+	RegisterContentBlock(typeTag, decodeAs[T, P])
+}
+
+// DecodeContentBlock decodes a content block with the decoder registered for its
+// type tag. Blocks with unregistered tags are decoded as UnknownContentBlock with
+// their original tag, so that newer gateway versions do not break decoding.
+func DecodeContentBlock(data []byte) (ContentBlock, error) {
+	var tag struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &tag); err != nil {
+		return nil, err
+	}
+	if tag.Type == "" {
+		return nil, fmt.Errorf("content block missing type field")
+	}
+
+	contentBlockRegistry.RLock()
+	decode, ok := contentBlockRegistry.decoders[tag.Type]
+	contentBlockRegistry.RUnlock()
+	if !ok {
+		return decodeUnregistered(data)
+	}
+	return decode(data)
+}
+
+// decodeUnregistered keeps a block of an unregistered type as UnknownContentBlock.
+// Blocks without a data field keep the whole object as Data.
+func decodeUnregistered(data []byte) (ContentBlock, error) {
+	var unknown UnknownContentBlock
+	if err := json.Unmarshal(data, &unknown); err != nil {
+		return nil, err
+	}
+	if unknown.Data == nil {
+		if err := json.Unmarshal(data, &unknown.Data); err != nil {
+			return nil, err
+		}
+	}
+	return &unknown, nil
+}
+
+// DecodeContentBlocks decodes a list of content blocks
+func DecodeContentBlocks(raw []json.RawMessage) ([]ContentBlock, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	blocks := make([]ContentBlock, len(raw))
+	for i, data := range raw {
+		block, err := DecodeContentBlock(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse content block %d: %w", i, err)
+		}
+		blocks[i] = block
+	}
+	return blocks, nil
+}
+
+// decodeAs decodes a content block with json.Unmarshal
+func decodeAs[T any, P interface {
+	*T
+	ContentBlock
+}](data []byte) (ContentBlock, error) {
+	var block T
+	if err := json.Unmarshal(data, &block); err != nil {
+		return nil, err
+	}
+	return P(&block), nil
+}
+
+func decodeText(data []byte) (ContentBlock, error) {
+	return decodeAs[Text](data)
+}
+
+func decodeRawText(data []byte) (ContentBlock, error) {
+	var raw struct {
+		Value *string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Value == nil {
+		return nil, fmt.Errorf("raw_text block missing value field")
+	}
+	return NewRawText(*raw.Value), nil
+}
+
+// mediaJSON holds the fields of image and file blocks, which carry either
+// base64 data or a URL. Stored inferences nest them under the type tag instead,
+// e.g. {"type":"file","file":{"url":...,"mime_type":...},"storage_path":...}.
+type mediaJSON struct {
+	Data     *string    `json:"data"`
+	URL      *string    `json:"url"`
+	MimeType *string    `json:"mime_type"`
+	Image    *mediaJSON `json:"image"`
+	File     *mediaJSON `json:"file"`
+}
+
+// flatten returns the fields of the stored shape, if the block has it
+func (m *mediaJSON) flatten(stored *mediaJSON) (*mediaJSON, bool) {
+	if m.Data != nil || m.URL != nil || stored == nil {
+		return m, false
+	}
+	return stored, true
+}
+
+func decodeImage(data []byte) (ContentBlock, error) {
+	var raw mediaJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	media, stored := raw.flatten(raw.Image)
+	if media.Data != nil {
+		if media.MimeType == nil {
+			return nil, fmt.Errorf("image block missing mime_type field")
+		}
+		return NewImageBase64(*media.Data, *media.MimeType), nil
+	}
+	if media.URL != nil {
+		img := NewImageURL(*media.URL)
+		img.MimeType = media.MimeType
+		return img, nil
+	}
+	if stored {
+		// Uploaded images are only kept in object storage
+		return decodeUnregistered(data)
+	}
+	return nil, fmt.Errorf("image block missing data or url field")
+}
+
+func decodeFile(data []byte) (ContentBlock, error) {
+	var raw mediaJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	media, stored := raw.flatten(raw.File)
+	if media.Data != nil {
+		if media.MimeType == nil {
+			return nil, fmt.Errorf("file block missing mime_type field")
+		}
+		return NewFileBase64(*media.Data, *media.MimeType), nil
+	}
+	if media.URL != nil {
+		file := NewFileURL(*media.URL)
+		file.MimeType = media.MimeType
+		return file, nil
+	}
+	if stored {
+		// Uploaded files are only kept in object storage
+		return decodeUnregistered(data)
+	}
+	return nil, fmt.Errorf("file block missing data or url field")
+}
+
+func decodeToolCall(data []byte) (ContentBlock, error) {
+	var raw struct {
+		ID           *string     `json:"id"`
+		RawArguments *string     `json:"raw_arguments"`
+		RawName      *string     `json:"raw_name"`
+		Arguments    interface{} `json:"arguments"`
+		Name         *string     `json:"name"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.ID == nil {
+		return nil, fmt.Errorf("tool_call block missing id field")
+	}
+	if raw.RawArguments == nil {
+		return nil, fmt.Errorf("tool_call block missing raw_arguments field")
+	}
+	if raw.RawName == nil {
+		return nil, fmt.Errorf("tool_call block missing raw_name field")
+	}
+
+	toolCall := NewToolCall(*raw.ID, *raw.RawArguments, *raw.RawName)
+	// Arguments that failed validation by the gateway are not an object
+	if args, ok := raw.Arguments.(map[string]interface{}); ok {
+		toolCall.Arguments = args
+	}
+	toolCall.Name = raw.Name
+	return toolCall, nil
+}
+
+func decodeToolResult(data []byte) (ContentBlock, error) {
+	var raw struct {
+		Name   *string `json:"name"`
+		Result *string `json:"result"`
+		ID     *string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Name == nil {
+		return nil, fmt.Errorf("tool_result block missing name field")
+	}
+	if raw.Result == nil {
+		return nil, fmt.Errorf("tool_result block missing result field")
+	}
+	if raw.ID == nil {
+		return nil, fmt.Errorf("tool_result block missing id field")
+	}
+	return tool.NewToolResult(*raw.Name, *raw.Result, *raw.ID), nil
+}
+
+func decodeThought(data []byte) (ContentBlock, error) {
+	return decodeAs[Thought](data)
+}
+
+func decodeUnknown(data []byte) (ContentBlock, error) {
+	var raw struct {
+		Data              interface{} `json:"data"`
+		ModelProviderName *string     `json:"model_provider_name"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Data == nil {
+		return nil, fmt.Errorf("unknown block missing data field")
+	}
+	unknown := NewUnknownContentBlock(raw.Data)
+	unknown.ModelProviderName = raw.ModelProviderName
+	return unknown, nil
+}
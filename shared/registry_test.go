@@ -0,0 +1,191 @@
+//go:build unit
+
+package shared
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/denkhaus/tensorzero/tool"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageMediaBlocksRoundTrip(t *testing.T) {
+	original := Message{
+		Role: "user",
+		Content: []ContentBlock{
+			NewText("What is in these?"),
+			NewImageURL("https://example.com/cat.png"),
+			NewImageBase64("aGk=", "image/png"),
+			NewFileURL("https://example.com/doc.pdf"),
+			NewFileBase64("aGk=", "application/pdf"),
+		},
+	}
+
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+
+	var decoded Message
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, original, decoded)
+
+	// ToMap produces the same type tags as json.Marshal
+	for _, block := range original.Content {
+		data, err := json.Marshal(block.ToMap())
+		require.NoError(t, err)
+		decoded, err := DecodeContentBlock(data)
+		require.NoError(t, err)
+		assert.Equal(t, block, decoded)
+	}
+}
+
+func TestDecodeContentBlockBuiltins(t *testing.T) {
+	tests := map[string]ContentBlock{
+		`{"type":"text","text":"hi"}`:                                                      NewText("hi"),
+		`{"type":"raw_text","value":"raw"}`:                                                NewRawText("raw"),
+		`{"type":"image","url":"u","mime_type":"image/png"}`:                               NewImageURLWithMimeType("u", "image/png"),
+		`{"type":"tool_call","id":"1","raw_name":"f","raw_arguments":"x","arguments":"x"}`: NewToolCall("1", "x", "f"),
+		`{"type":"tool_result","name":"f","result":"ok","id":"1"}`:                         tool.NewToolResult("f", "ok", "1"),
+		`{"type":"thought","text":"hmm"}`:                                                  NewThought("hmm"),
+		`{"type":"unknown","data":{"x":1}}`:                                                NewUnknownContentBlock(map[string]interface{}{"x": float64(1)}),
+		`{"type":"tool_code","language":"go","code":"x"}`:                                  &tool.ToolCodeContent{Language: "go", Code: "x", Type: "tool_code"},
+		`{"type":"tool_output","output":"x"}`:                                              &tool.ToolOutputContent{Output: "x", Type: "tool_output"},
+	}
+	for data, want := range tests {
+		t.Run(want.GetType(), func(t *testing.T) {
+			block, err := DecodeContentBlock([]byte(data))
+			require.NoError(t, err)
+			assert.Equal(t, want, block)
+		})
+	}
+}
+
+func TestDecodeContentBlockErrors(t *testing.T) {
+	for _, data := range []string{
+		`"text"`,
+		`{"text":"hi"}`,
+		`{"type":"image"}`,
+		`{"type":"image","data":"aGk="}`,
+		`{"type":"file"}`,
+		`{"type":"raw_text"}`,
+		`{"type":"tool_call","id":"1"}`,
+		`{"type":"tool_result","name":"f"}`,
+		`{"type":"unknown"}`,
+	} {
+		t.Run(data, func(t *testing.T) {
+			_, err := DecodeContentBlock([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+
+	var msg Message
+	assert.Error(t, json.Unmarshal([]byte(`{"role":"user","content":[{"type":"image"}]}`), &msg))
+}
+
+func TestDecodeContentBlockUnregistered(t *testing.T) {
+	block, err := DecodeContentBlock([]byte(`{"type":"audio","data":{"format":"wav"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, &UnknownContentBlock{Data: map[string]interface{}{"format": "wav"}, Type: "audio"}, block)
+
+	// Blocks without a data field are kept whole
+	var msg Message
+	require.NoError(t, json.Unmarshal([]byte(`{"role":"user","content":[{"type":"video","url":"u"}]}`), &msg))
+	require.Len(t, msg.Content, 1)
+	assert.Equal(t, &UnknownContentBlock{
+		Data: map[string]interface{}{"type": "video", "url": "u"},
+		Type: "video",
+	}, msg.Content[0])
+}
+
+func TestDecodeContentBlockStoredMedia(t *testing.T) {
+	storedFile := `{
+		"type": "file",
+		"file": {"url": "https://example.com/doc.pdf", "mime_type": "application/pdf"},
+		"storage_path": {"kind": {"type": "s3_compatible", "bucket_name": "tz"}, "path": "observability/files/abc.pdf"}
+	}`
+	block, err := DecodeContentBlock([]byte(storedFile))
+	require.NoError(t, err)
+	want := NewFileURL("https://example.com/doc.pdf")
+	want.MimeType = util.StringPtr("application/pdf")
+	assert.Equal(t, want, block)
+
+	storedImage := `{
+		"type": "image",
+		"image": {"url": "https://example.com/cat.png", "mime_type": "image/png"},
+		"storage_path": {"kind": {"type": "filesystem", "path": "/tmp"}, "path": "observability/images/abc.png"}
+	}`
+	block, err = DecodeContentBlock([]byte(storedImage))
+	require.NoError(t, err)
+	assert.Equal(t, NewImageURLWithMimeType("https://example.com/cat.png", "image/png"), block)
+
+	// Uploads without a URL only exist in object storage and are kept whole
+	uploaded := `{"type":"file","file":{"url":null,"mime_type":"application/pdf"},"storage_path":{"kind":{"type":"disabled"},"path":"abc.pdf"}}`
+	block, err = DecodeContentBlock([]byte(uploaded))
+	require.NoError(t, err)
+	unknown, ok := block.(*UnknownContentBlock)
+	require.True(t, ok)
+	assert.Equal(t, "file", unknown.Type)
+	assert.Contains(t, unknown.Data, "storage_path")
+}
+
+func TestDecodeContentBlockLegacyMediaTags(t *testing.T) {
+	tests := []struct {
+		data string
+		want ContentBlock
+	}{
+		{`{"type":"image_url","url":"https://example.com/cat.png"}`, NewImageURL("https://example.com/cat.png")},
+		{`{"type":"image_base64","data":"aGk=","mime_type":"image/png"}`, NewImageBase64("aGk=", "image/png")},
+		{`{"type":"file_url","url":"https://example.com/doc.pdf"}`, NewFileURL("https://example.com/doc.pdf")},
+		{`{"type":"file_base64","data":"aGk=","mime_type":"application/pdf"}`, NewFileBase64("aGk=", "application/pdf")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			block, err := DecodeContentBlock([]byte(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, block)
+		})
+	}
+}
+
+type citation struct {
+	Source string `json:"source"`
+	Type   string `json:"type"`
+}
+
+func (c *citation) GetType() string { return c.Type }
+func (c *citation) ToMap() map[string]interface{} {
+	return map[string]interface{}{"type": c.Type, "source": c.Source}
+}
+
+func TestRegisterContentBlockType(t *testing.T) {
+	RegisterContentBlockType[citation]("test_citation")
+
+	var msg Message
+	require.NoError(t, json.Unmarshal([]byte(`{"role":"assistant","content":[{"type":"test_citation","source":"wiki"}]}`), &msg))
+	require.Len(t, msg.Content, 1)
+	assert.Equal(t, &citation{Source: "wiki", Type: "test_citation"}, msg.Content[0])
+
+	assert.Panics(t, func() { RegisterContentBlockType[citation]("test_citation") })
+	assert.Panics(t, func() { RegisterContentBlock("text", decodeText) })
+	assert.Panics(t, func() { RegisterContentBlock("", decodeText) })
+	assert.Panics(t, func() { RegisterContentBlock("test_nil", nil) })
+}
+
+func TestRegisterContentBlock(t *testing.T) {
+	RegisterContentBlock("test_upper", func(data []byte) (ContentBlock, error) {
+		var raw struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		return NewText(raw.Text + "!"), nil
+	})
+
+	block, err := DecodeContentBlock([]byte(`{"type":"test_upper","text":"hi"}`))
+	require.NoError(t, err)
+	assert.Equal(t, NewText("hi!"), block)
+}
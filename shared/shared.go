@@ -2,9 +2,8 @@ package shared
 
 import (
 	"encoding/json"
-	"fmt"
 
-	"github.com/denkhaus/tensorzero/tool"
+	"github.com/denkhaus/tensorzero/errors"
 )
 
 // System represents system content
@@ -177,8 +176,9 @@ func (f *FileBase64) ToMap() map[string]interface{} {
 
 // FileURL represents file content from URL
 type FileURL struct {
-	URL  string `json:"url"`
-	Type string `json:"type"`
+	URL      string  `json:"url"`
+	MimeType *string `json:"mime_type,omitempty"`
+	Type     string  `json:"type"`
 }
 
 func NewFileURL(url string) *FileURL {
@@ -193,10 +193,14 @@ func (f *FileURL) GetType() string {
 }
 
 func (f *FileURL) ToMap() map[string]interface{} {
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"type": f.Type,
 		"url":  f.URL,
 	}
+	if f.MimeType != nil {
+		result["mime_type"] = *f.MimeType
+	}
+	return result
 }
 
 // ToolCall represents a tool call
@@ -306,7 +310,8 @@ type Message struct {
 
 // UnmarshalJSON implements the json.Unmarshaler interface for Message.
 // This custom unmarshaler is needed to correctly unmarshal the 'Content' field,
-// which is a slice of the ContentBlock interface.
+// which is a slice of the ContentBlock interface. Blocks are decoded with the
+// decoders registered for their type tags, see RegisterContentBlock.
 func (m *Message) UnmarshalJSON(data []byte) error {
 	var raw struct {
 		Role    string            `json:"role"`
@@ -317,94 +322,13 @@ func (m *Message) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	m.Role = raw.Role
-	m.Content = make([]ContentBlock, len(raw.Content))
-
-	for i, rawBlock := range raw.Content {
-		var typeField struct {
-			Type string `json:"type"`
-		}
-		if err := json.Unmarshal(rawBlock, &typeField); err != nil {
-			return err
-		}
-
-		var contentBlock ContentBlock
-		switch typeField.Type {
-		case "text":
-			var text Text
-			if err := json.Unmarshal(rawBlock, &text); err != nil {
-				return err
-			}
-			contentBlock = &text
-		case "image_url":
-			var image ImageURL
-			if err := json.Unmarshal(rawBlock, &image); err != nil {
-				return err
-			}
-			contentBlock = &image
-		case "image_base64":
-			var image ImageBase64
-			if err := json.Unmarshal(rawBlock, &image); err != nil {
-				return err
-			}
-			contentBlock = &image
-		case "tool_code":
-			var toolCode tool.ToolCodeContent
-			if err := json.Unmarshal(rawBlock, &toolCode); err != nil {
-				return err
-			}
-			contentBlock = &toolCode
-		case "tool_output":
-			var toolOutput tool.ToolOutputContent
-			if err := json.Unmarshal(rawBlock, &toolOutput); err != nil {
-				return err
-			}
-			contentBlock = &toolOutput
-		case "tool_call":
-			var toolCall ToolCall
-			if err := json.Unmarshal(rawBlock, &toolCall); err != nil {
-				return err
-			}
-			contentBlock = &toolCall
-		case "thought":
-			var thought Thought
-			if err := json.Unmarshal(rawBlock, &thought); err != nil {
-				return err
-			}
-			contentBlock = &thought
-		case "tool_result":
-			var toolResult tool.ToolResult
-			if err := json.Unmarshal(rawBlock, &toolResult); err != nil {
-				return err
-			}
-			contentBlock = &toolResult
-		case "raw_text":
-			var rawText RawText
-			if err := json.Unmarshal(rawBlock, &rawText); err != nil {
-				return err
-			}
-			contentBlock = &rawText
-		case "file_url":
-			var fileURL FileURL
-			if err := json.Unmarshal(rawBlock, &fileURL); err != nil {
-				return err
-			}
-			contentBlock = &fileURL
-		case "file_base64":
-			var fileBase64 FileBase64
-			if err := json.Unmarshal(rawBlock, &fileBase64); err != nil {
-				return err
-			}
-			contentBlock = &fileBase64
-		default:
-			var unknown UnknownContentBlock
-			if err := json.Unmarshal(rawBlock, &unknown); err != nil {
-				return err
-			}
-			contentBlock = &unknown
-		}
-		m.Content[i] = contentBlock
+	content, err := DecodeContentBlocks(raw.Content)
+	if err != nil {
+		return err
 	}
+
+	m.Role = raw.Role
+	m.Content = content
 	return nil
 }
 
@@ -429,9 +353,9 @@ type ThoughtChunk struct {
 func (tc *ThoughtChunk) GetType() string { return tc.Type }
 func (tc *ThoughtChunk) GetID() string   { return tc.ID }
 
-// ExtraBody represents extra body content for requests
-
 // VariantExtraBody represents variant-specific extra body
+//
+// Deprecated: use inference.SetExtraBody or inference.DeleteExtraBody with ForVariant.
 type VariantExtraBody struct {
 	VariantName string      `json:"variant_name"`
 	Pointer     string      `json:"pointer"`
@@ -440,6 +364,8 @@ type VariantExtraBody struct {
 }
 
 // ProviderExtraBody represents provider-specific extra body
+//
+// Deprecated: use inference.SetExtraBody or inference.DeleteExtraBody with ForProvider.
 type ProviderExtraBody struct {
 	ModelProviderName string      `json:"model_provider_name"`
 	Pointer           string      `json:"pointer"`
@@ -447,24 +373,12 @@ type ProviderExtraBody struct {
 	Delete            *bool       `json:"delete,omitempty"`
 }
 
-// TensorZeroError represents an error from TensorZero
-type TensorZeroError struct {
-	StatusCode int
-	Text       string
-}
-
-func (e *TensorZeroError) Error() string {
-	return fmt.Sprintf("TensorZeroError (status code %d): %s", e.StatusCode, e.Text)
-}
+// TensorZeroError represents an error from TensorZero.
+// It is an alias of errors.TensorZeroError, the type returned by the gateway client.
+type TensorZeroError = errors.TensorZeroError
 
 // TensorZeroInternalError represents an internal error
-type TensorZeroInternalError struct {
-	Message string
-}
-
-func (e *TensorZeroInternalError) Error() string {
-	return e.Message
-}
+type TensorZeroInternalError = errors.TensorZeroInternalError
 
 // OrderBy specifies ordering for list inferences
 type OrderBy struct {
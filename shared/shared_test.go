@@ -183,7 +183,7 @@ func TestTensorZeroError(t *testing.T) {
 	err := &TensorZeroError{StatusCode: 400, Text: "Bad Request"}
 	assert.Equal(t, 400, err.StatusCode)
 	assert.Equal(t, "Bad Request", err.Text)
-	assert.Equal(t, "TensorZeroError (status code 400): Bad Request", err.Error())
+	assert.Equal(t, "TensorZero API error (status 400): Bad Request", err.Error())
 }
 
 func TestTensorZeroInternalError(t *testing.T) {
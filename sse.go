@@ -2,10 +2,22 @@ package tensorzero
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"strings"
+	"net/http"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
 )
 
+// DefaultMaxEventSize is the default limit for the size of a single SSE event
+const DefaultMaxEventSize = 16 << 20
+
+// ErrEventTooLarge is returned when an SSE event exceeds the maximum event size
+var ErrEventTooLarge = errors.New("sse: event too large")
+
 // SSEEvent represents a Server-Sent Event
 type SSEEvent struct {
 	Event string
@@ -14,79 +26,203 @@ type SSEEvent struct {
 	Retry string
 }
 
-// SSEScanner scans Server-Sent Events from a reader
+// SSEOption represents configuration options for SSEScanner
+type SSEOption func(*SSEScanner)
+
+// WithMaxEventSize limits the size of a single event, including all of its
+// lines. Larger events fail the scan with ErrEventTooLarge.
+func WithMaxEventSize(size int) SSEOption {
+	return func(s *SSEScanner) {
+		s.maxEventSize = size
+	}
+}
+
+// SSEScanner scans Server-Sent Events from a reader. The buffers holding an
+// event are reused, so Data is only valid until the next call to Scan.
 type SSEScanner struct {
-	scanner *bufio.Scanner
-	event   SSEEvent
-	err     error
+	reader       *bufio.Reader
+	maxEventSize int
+
+	line  []byte
+	event struct {
+		name, id, retry []byte
+		data            bytes.Buffer
+		hasData         bool
+	}
+	size int
+	err  error
 }
 
 // NewSSEScanner creates a new SSE scanner
-func NewSSEScanner(r io.Reader) *SSEScanner {
-	return &SSEScanner{
-		scanner: bufio.NewScanner(r),
+func NewSSEScanner(r io.Reader, options ...SSEOption) *SSEScanner {
+	s := &SSEScanner{
+		reader:       bufio.NewReaderSize(r, 64<<10),
+		maxEventSize: DefaultMaxEventSize,
 	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
 }
 
-// Scan scans the next SSE event
+// Scan scans the next SSE event. A final event that is not terminated by an
+// empty line is dispatched when the reader is exhausted.
 func (s *SSEScanner) Scan() bool {
-	s.event = SSEEvent{}
+	if s.err != nil {
+		return false
+	}
+	s.reset()
 
-	for s.scanner.Scan() {
-		line := s.scanner.Text()
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			if err == io.EOF {
+				return s.pending()
+			}
+			s.err = err
+			return false
+		}
 
-		// Empty line indicates end of event
-		if line == "" {
-			return true
+		// Empty line dispatches the event
+		if len(line) == 0 {
+			if s.pending() {
+				return true
+			}
+			continue
 		}
 
 		// Skip comments
-		if strings.HasPrefix(line, ":") {
+		if line[0] == ':' {
 			continue
 		}
 
-		// Parse field
-		if colonIndex := strings.Index(line, ":"); colonIndex != -1 {
-			field := line[:colonIndex]
-			value := line[colonIndex+1:]
+		s.size += len(line)
+		if s.maxEventSize > 0 && s.size > s.maxEventSize {
+			s.err = fmt.Errorf("%w: exceeds %d bytes", ErrEventTooLarge, s.maxEventSize)
+			return false
+		}
 
+		field, value := line, []byte(nil)
+		if colonIndex := bytes.IndexByte(line, ':'); colonIndex != -1 {
+			field, value = line[:colonIndex], line[colonIndex+1:]
 			// Remove leading space from value
 			if len(value) > 0 && value[0] == ' ' {
 				value = value[1:]
 			}
+		}
 
-			switch field {
-			case "event":
-				s.event.Event = value
-			case "data":
-				if s.event.Data != "" {
-					s.event.Data += "\n"
-				}
-				s.event.Data += value
-			case "id":
-				s.event.ID = value
-			case "retry":
-				s.event.Retry = value
-			}
-		} else {
-			// Field without colon (treat as data)
-			if s.event.Data != "" {
-				s.event.Data += "\n"
+		switch string(field) {
+		case "event":
+			s.event.name = append(s.event.name[:0], value...)
+		case "data":
+			if s.event.hasData {
+				s.event.data.WriteByte('\n')
 			}
-			s.event.Data += line
+			s.event.data.Write(value)
+			s.event.hasData = true
+		case "id":
+			s.event.id = append(s.event.id[:0], value...)
+		case "retry":
+			s.event.retry = append(s.event.retry[:0], value...)
+		}
+	}
+}
+
+// readLine returns the next line without its line ending. The line is only
+// valid until the next call.
+func (s *SSEScanner) readLine() ([]byte, error) {
+	s.line = s.line[:0]
+	for {
+		chunk, err := s.reader.ReadSlice('\n')
+		s.line = append(s.line, chunk...)
+		// Allow for the line ending on top of the event size
+		if s.maxEventSize > 0 && len(s.line) > s.maxEventSize+2 {
+			return nil, fmt.Errorf("%w: exceeds %d bytes", ErrEventTooLarge, s.maxEventSize)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && len(s.line) > 0 {
+			break
+		}
+		if err != nil {
+			return nil, err
 		}
+		break
 	}
 
-	s.err = s.scanner.Err()
-	return false
+	line := bytes.TrimSuffix(s.line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r")), nil
+}
+
+// pending reports whether an event has been read since the last reset
+func (s *SSEScanner) pending() bool {
+	return s.event.hasData || len(s.event.name) > 0 || len(s.event.id) > 0 || len(s.event.retry) > 0
+}
+
+func (s *SSEScanner) reset() {
+	s.event.name = s.event.name[:0]
+	s.event.id = s.event.id[:0]
+	s.event.retry = s.event.retry[:0]
+	s.event.data.Reset()
+	s.event.hasData = false
+	s.size = 0
 }
 
 // Event returns the current event
 func (s *SSEScanner) Event() SSEEvent {
-	return s.event
+	return SSEEvent{
+		Event: string(s.event.name),
+		Data:  s.event.data.String(),
+		ID:    string(s.event.id),
+		Retry: string(s.event.retry),
+	}
+}
+
+// EventType returns the event field of the current event without allocating
+func (s *SSEScanner) EventType() []byte {
+	return s.event.name
+}
+
+// Data returns the data of the current event without copying it. The slice is
+// only valid until the next call to Scan.
+func (s *SSEScanner) Data() []byte {
+	return s.event.data.Bytes()
 }
 
 // Err returns any scanning error
 func (s *SSEScanner) Err() error {
 	return s.err
 }
+
+// doneEvent is the data of the event that terminates a stream
+var doneEvent = []byte("[DONE]")
+
+// WithMaxStreamEventSize limits the size of a single event of an inference
+// stream. Defaults to DefaultMaxEventSize.
+func WithMaxStreamEventSize(size int) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.maxEventSize = size
+	}
+}
+
+// streamError returns the error carried by an "error" event or by a data
+// payload that holds an error object instead of a chunk
+func streamError(eventType, data []byte, header http.Header) error {
+	isErrorEvent := string(eventType) == "error"
+	if !isErrorEvent && !bytes.Contains(data, []byte(`"error"`)) {
+		return nil
+	}
+
+	var payload struct {
+		Error       json.RawMessage `json:"error"`
+		InferenceID json.RawMessage `json:"inference_id"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil || len(payload.Error) == 0 || payload.InferenceID != nil {
+		if isErrorEvent {
+			return tzerrors.ParseAPIError(0, header, data)
+		}
+		return nil
+	}
+	return tzerrors.ParseAPIError(0, header, data)
+}
@@ -0,0 +1,145 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scanAll(t *testing.T, input string, options ...SSEOption) ([]SSEEvent, error) {
+	t.Helper()
+	scanner := NewSSEScanner(strings.NewReader(input), options...)
+	var events []SSEEvent
+	for scanner.Scan() {
+		events = append(events, scanner.Event())
+	}
+	return events, scanner.Err()
+}
+
+func TestSSEScannerFields(t *testing.T) {
+	events, err := scanAll(t, ": comment\nevent: update\nid: 7\nretry: 100\ndata: line 1\ndata:line 2\n\n\n\ndata: second\r\n\r\n")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, SSEEvent{Event: "update", Data: "line 1\nline 2", ID: "7", Retry: "100"}, events[0])
+	assert.Equal(t, SSEEvent{Data: "second"}, events[1])
+}
+
+func TestSSEScannerUnterminatedEvent(t *testing.T) {
+	events, err := scanAll(t, "data: first\n\ndata: last")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "last", events[1].Data)
+}
+
+func TestSSEScannerLargeEvent(t *testing.T) {
+	payload := strings.Repeat("x", 1<<20)
+	events, err := scanAll(t, "data: "+payload+"\n\n")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, payload, events[0].Data)
+}
+
+func TestSSEScannerMaxEventSize(t *testing.T) {
+	_, err := scanAll(t, "data: "+strings.Repeat("x", 200)+"\n\n", WithMaxEventSize(100))
+	assert.ErrorIs(t, err, ErrEventTooLarge)
+
+	// The limit applies to the whole event, not only to single lines
+	_, err = scanAll(t, strings.Repeat("data: "+strings.Repeat("x", 40)+"\n", 5)+"\n", WithMaxEventSize(100))
+	assert.ErrorIs(t, err, ErrEventTooLarge)
+}
+
+func TestInferenceStreamDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(streamEvent))
+		w.Write([]byte("data: [DONE]\n\n"))
+		w.Write([]byte("data: not json\n\n"))
+	}))
+	defer server.Close()
+
+	chunks, errs := NewHTTPGateway(server.URL).InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	count := 0
+	for range chunks {
+		count++
+	}
+	require.NoError(t, <-errs)
+	assert.Equal(t, 1, count)
+}
+
+func TestInferenceStreamErrorEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+	}{
+		{name: "error event", event: "event: error\ndata: {\"error\": \"All variants failed with errors: timeout\"}\n\n"},
+		{name: "error payload", event: "data: {\"error\": \"All variants failed with errors: timeout\"}\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("X-Request-Id", "req-7")
+				w.Write([]byte(streamEvent))
+				w.Write([]byte(tt.event))
+				w.Write([]byte(streamEvent))
+			}))
+			defer server.Close()
+
+			chunks, errs := NewHTTPGateway(server.URL).InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+			count := 0
+			for range chunks {
+				count++
+			}
+			err := <-errs
+			assert.Equal(t, 1, count)
+
+			var tzErr *tzerrors.TensorZeroError
+			require.ErrorAs(t, err, &tzErr)
+			assert.Equal(t, "All variants failed with errors: timeout", tzErr.Message)
+			assert.Equal(t, "req-7", tzErr.RequestID)
+			assert.True(t, errors.Is(err, tzerrors.ErrAllVariantsFailed))
+		})
+	}
+}
+
+func TestInferenceStreamMaxEventSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"raw\": \"" + strings.Repeat("x", 4096) + "\"}\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL, WithMaxStreamEventSize(1024))
+	chunks, errs := client.InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	for range chunks {
+	}
+	assert.ErrorIs(t, <-errs, ErrEventTooLarge)
+}
+
+func BenchmarkSSEScanner(b *testing.B) {
+	input := strings.Repeat(streamEvent, 1000)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		scanner := NewSSEScanner(strings.NewReader(input))
+		for scanner.Scan() {
+			_ = scanner.Data()
+		}
+		if err := scanner.Err(); err != nil && err != io.EOF {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package tensorzero
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/denkhaus/tensorzero/inference"
+)
+
+// Stream is a pull-based stream of inference chunks:
+//
+//	stream, err := tensorzero.OpenStream(ctx, client, req)
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//
+//	for stream.Next() {
+//		chunk := stream.Current()
+//		// Process chunk
+//	}
+//	if err := stream.Err(); err != nil {
+//		return err
+//	}
+//
+// A Stream is not safe for concurrent use, except that Close may be called
+// from another goroutine to abort a blocked Next.
+type Stream struct {
+	next   func() (inference.InferenceChunk, error)
+	finish func(err error)
+
+	current inference.InferenceChunk
+	err     error
+	done    bool
+
+	closed     atomic.Bool
+	finishOnce sync.Once
+}
+
+// NewStream creates a Stream from a function returning the next chunk, or
+// io.EOF at the end of the stream, and a function releasing its resources.
+// It lets other Gateway implementations provide streams.
+func NewStream(next func() (inference.InferenceChunk, error), close func() error) *Stream {
+	return newStream(next, func(error) {
+		if close != nil {
+			close()
+		}
+	})
+}
+
+// newStream creates a Stream whose finish function is called exactly once
+// with the final error when the stream ends or is closed
+func newStream(next func() (inference.InferenceChunk, error), finish func(err error)) *Stream {
+	return &Stream{next: next, finish: finish}
+}
+
+// streamDrainTimeout bounds how long closing a channel-based stream waits
+// for its producer to stop
+var streamDrainTimeout = 5 * time.Second
+
+// OpenStream makes a streaming inference request on gw. It uses the Stream
+// method of gateways implementing Streamer and adapts InferenceStream otherwise.
+func OpenStream(ctx context.Context, gw Gateway, req *inference.InferenceRequest) (*Stream, error) {
+	if streamer, ok := gw.(Streamer); ok {
+		return streamer.Stream(ctx, req)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	chunks, errs := gw.InferenceStream(ctx, req)
+	return openChannels(chunks, errs, cancel)
+}
+
+// StreamFromChannels adapts the channels returned by InferenceStream to a
+// Stream. cancel is called on Close and must stop the producer of the channels.
+// Close then waits for the chunk channel to be closed so that the producer's
+// resources are released, but no longer than a few seconds.
+func StreamFromChannels(chunks <-chan inference.InferenceChunk, errs <-chan error, cancel context.CancelFunc) *Stream {
+	next := func() (inference.InferenceChunk, error) {
+		if chunk, ok := <-chunks; ok {
+			return chunk, nil
+		}
+		if err := <-errs; err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return newStream(next, func(error) {
+		if cancel != nil {
+			cancel()
+		}
+		// Wait for the producer to finish so that its resources are released
+		timeout := time.NewTimer(streamDrainTimeout)
+		defer timeout.Stop()
+		for {
+			select {
+			case _, ok := <-chunks:
+				if !ok {
+					return
+				}
+			case <-timeout.C:
+				return
+			}
+		}
+	})
+}
+
+// openChannels adapts the channels of a streaming request like
+// StreamFromChannels, but waits for the first chunk: a request that fails
+// before streaming returns its error, as the Stream method of Streamer does.
+func openChannels(chunks <-chan inference.InferenceChunk, errs <-chan error, cancel context.CancelFunc) (*Stream, error) {
+	first, ok := <-chunks
+	if !ok {
+		err := <-errs
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		return newStream(func() (inference.InferenceChunk, error) { return nil, io.EOF }, func(error) {}), nil
+	}
+
+	stream := StreamFromChannels(chunks, errs, cancel)
+	next, pending := stream.next, true
+	stream.next = func() (inference.InferenceChunk, error) {
+		if pending {
+			pending = false
+			return first, nil
+		}
+		return next()
+	}
+	return stream, nil
+}
+
+// Next advances to the next chunk. It returns false at the end of the stream,
+// after an error or after Close.
+func (s *Stream) Next() bool {
+	if s.done || s.closed.Load() {
+		return false
+	}
+
+	chunk, err := s.next()
+	if err != nil {
+		s.done = true
+		s.current = nil
+		if err == io.EOF || s.closed.Load() {
+			err = nil
+		}
+		s.err = err
+		s.end(err)
+		return false
+	}
+
+	s.current = chunk
+	return true
+}
+
+// Current returns the chunk read by the last call to Next
+func (s *Stream) Current() inference.InferenceChunk {
+	return s.current
+}
+
+// Err returns the error that ended the stream, or nil if it ended normally or was closed
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Close cancels the request and releases the response body. It is safe to
+// call Close several times and after the stream has ended.
+func (s *Stream) Close() error {
+	s.closed.Store(true)
+	s.end(nil)
+	return nil
+}
+
+func (s *Stream) end(err error) {
+	s.finishOnce.Do(func() {
+		s.finish(err)
+	})
+}
+
+// All returns an iterator over the chunks of the stream. An error ends the
+// iteration with a nil chunk and the error. The stream is closed when the
+// iteration ends, including when the loop is left early.
+func (s *Stream) All() iter.Seq2[inference.InferenceChunk, error] {
+	return func(yield func(inference.InferenceChunk, error) bool) {
+		defer s.Close()
+		for s.Next() {
+			if !yield(s.Current(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Accumulate reads the remaining chunks and assembles them into the final
+// response. If the stream fails, the response assembled so far is returned
+// together with the error. The stream is closed when Accumulate returns.
+func (s *Stream) Accumulate() (inference.InferenceResponse, error) {
+	var acc inference.Accumulator
+	for chunk, err := range s.All() {
+		if err != nil {
+			return acc.Response(), err
+		}
+		if err := acc.Add(chunk); err != nil {
+			return acc.Response(), err
+		}
+	}
+	return acc.Response(), nil
+}
+
+// forward sends the chunks of the stream to out until the stream ends or ctx is done
+func (s *Stream) forward(ctx context.Context, out chan<- inference.InferenceChunk) error {
+	for s.Next() {
+		select {
+		case out <- s.Current():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return s.Err()
+}
+
+// chunkReader reads inference chunks from an SSE response. The stream ends
+// with the end of the body or with a [DONE] event; an error event ends it
+// with the error reported by the gateway.
+type chunkReader struct {
+	ctx      context.Context
+	resp     *http.Response
+	scanner  *SSEScanner
+	watchdog *streamWatchdog
+	ids      streamIDs
+	started  bool
+}
+
+// next returns the next chunk, or io.EOF at the end of the stream
+func (c *chunkReader) next() (inference.InferenceChunk, error) {
+	// The time spent by the consumer between two chunks does not count as idle time
+	c.resume()
+
+	for c.scanner.Scan() {
+		data := bytes.TrimSpace(c.scanner.Data())
+		if len(data) == 0 {
+			c.resume()
+			continue
+		}
+		if bytes.Equal(data, doneEvent) {
+			return nil, io.EOF
+		}
+		if err := streamError(c.scanner.EventType(), data, c.resp.Header); err != nil {
+			return nil, err
+		}
+		c.watchdog.pause()
+
+		chunk, err := inference.DecodeInferenceChunk(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse chunk: %w", err)
+		}
+		c.ids.observe(chunk)
+		c.started = true
+		return chunk, nil
+	}
+
+	if err := c.scanner.Err(); err != nil {
+		return nil, timeoutCause(c.ctx, fmt.Errorf("failed to read stream: %w", err))
+	}
+	if err := timeoutCause(c.ctx, c.ctx.Err()); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// resume restarts the idle timer once the first chunk has been received
+func (c *chunkReader) resume() {
+	if c.started {
+		c.watchdog.feed()
+	}
+}
@@ -0,0 +1,290 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamNext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(streamEvent))
+		w.Write([]byte(streamEvent))
+	}))
+	defer server.Close()
+
+	stream, err := OpenStream(context.Background(), NewHTTPGateway(server.URL), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	count := 0
+	for stream.Next() {
+		assert.Equal(t, "v1", stream.Current().GetVariantName())
+		count++
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, 2, count)
+	assert.Nil(t, stream.Current())
+	assert.False(t, stream.Next())
+}
+
+func TestStreamRequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Unknown function: missing"}`))
+	}))
+	defer server.Close()
+
+	stream, err := OpenStream(context.Background(), NewHTTPGateway(server.URL), &inference.InferenceRequest{FunctionName: util.StringPtr("missing")})
+	assert.Nil(t, stream)
+	assert.ErrorIs(t, err, tzerrors.ErrUnknownFunction)
+}
+
+func TestStreamNilRequest(t *testing.T) {
+	stream, err := OpenStream(context.Background(), NewHTTPGateway("http://localhost:1"), nil)
+	assert.Nil(t, stream)
+	var validationErr *tzerrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "request", validationErr.Field)
+}
+
+func TestStreamErrorEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(streamEvent))
+		w.Write([]byte("event: error\ndata: {\"error\": \"provider failed\"}\n\n"))
+	}))
+	defer server.Close()
+
+	stream, err := OpenStream(context.Background(), NewHTTPGateway(server.URL), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var chunks int
+	var errs []error
+	for chunk, err := range stream.All() {
+		if err != nil {
+			errs = append(errs, err)
+			assert.Nil(t, chunk)
+			continue
+		}
+		chunks++
+	}
+	assert.Equal(t, 1, chunks)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "provider failed")
+}
+
+func TestStreamCloseAbortsRequest(t *testing.T) {
+	released := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(streamEvent))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(released)
+	}))
+	defer server.Close()
+
+	stream, err := OpenStream(context.Background(), NewHTTPGateway(server.URL), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	require.NoError(t, err)
+	require.True(t, stream.Next())
+
+	// Close aborts a Next blocked on the network from another goroutine
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		stream.Close()
+	}()
+	assert.False(t, stream.Next())
+	assert.NoError(t, stream.Err())
+
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("request was not canceled")
+	}
+	assert.NoError(t, stream.Close())
+}
+
+func TestStreamCloseWhileReading(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for r.Context().Err() == nil {
+			w.Write([]byte(streamEvent))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	logger, buf := newTestLogger(slog.LevelInfo)
+	stream, err := OpenStream(context.Background(), NewHTTPGateway(server.URL, WithLogger(logger)), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	require.NoError(t, err)
+
+	// The stream is logged on Close while the reader keeps observing chunks
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for stream.Next() {
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, stream.Close())
+	<-done
+
+	assert.Contains(t, buf.String(), "chunks=")
+}
+
+func TestStreamFromChannelsCloseStopsWaiting(t *testing.T) {
+	defer func(timeout time.Duration) { streamDrainTimeout = timeout }(streamDrainTimeout)
+	streamDrainTimeout = 20 * time.Millisecond
+
+	// The producer ignores cancel and never closes its channels
+	chunkCh := make(chan inference.InferenceChunk)
+	stream := StreamFromChannels(chunkCh, make(chan error), func() {})
+
+	closed := make(chan struct{})
+	go func() {
+		stream.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close waited for the producer without bound")
+	}
+}
+
+func TestStreamAllBreak(t *testing.T) {
+	closed := false
+	chunks := []inference.InferenceChunk{&inference.ChatChunk{}, &inference.ChatChunk{}, &inference.ChatChunk{}}
+	stream := NewStream(func() (inference.InferenceChunk, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+		chunk := chunks[0]
+		chunks = chunks[1:]
+		return chunk, nil
+	}, func() error {
+		closed = true
+		return nil
+	})
+
+	for range stream.All() {
+		break
+	}
+	assert.True(t, closed)
+	assert.False(t, stream.Next())
+}
+
+func TestStreamAccumulate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(streamEvent))
+		w.Write([]byte("data: {\"inference_id\":\"550e8400-e29b-41d4-a716-446655440000\",\"episode_id\":\"550e8400-e29b-41d4-a716-446655440001\",\"variant_name\":\"v1\",\"raw\":\"}\",\"usage\":{\"input_tokens\":3,\"output_tokens\":2}}\n\n"))
+	}))
+	defer server.Close()
+
+	stream, err := OpenStream(context.Background(), NewHTTPGateway(server.URL), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	require.NoError(t, err)
+
+	resp, err := stream.Accumulate()
+	require.NoError(t, err)
+	jsonResp, ok := resp.(*inference.JsonInferenceResponse)
+	require.True(t, ok)
+	assert.Equal(t, "{}", *jsonResp.Output.Raw)
+	assert.NotNil(t, jsonResp.Output.Parsed)
+	assert.Equal(t, 2, jsonResp.Usage.OutputTokens)
+}
+
+func TestStreamAccumulateError(t *testing.T) {
+	chunkCh := make(chan inference.InferenceChunk, 1)
+	errCh := make(chan error, 1)
+	chunkCh <- &inference.JsonChunk{VariantName: "v1", Raw: "{\"a\""}
+	close(chunkCh)
+	errCh <- errors.New("broken")
+	close(errCh)
+
+	resp, err := StreamFromChannels(chunkCh, errCh, func() {}).Accumulate()
+	assert.EqualError(t, err, "broken")
+	require.NotNil(t, resp)
+	assert.Equal(t, "{\"a\"", *resp.(*inference.JsonInferenceResponse).Output.Raw)
+}
+
+func TestStreamErrorsUpFront(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Unknown function: missing"}`))
+	}))
+	defer server.Close()
+
+	pool, err := NewPoolGateway([]string{server.URL}, WithHealthCheckInterval(0))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// All gateways return errors of the request before streaming from Stream
+	gateways := map[string]Gateway{
+		"http":    NewHTTPGateway(server.URL),
+		"chain":   Chain(NewHTTPGateway(server.URL)),
+		"pool":    pool,
+		"adapted": struct{ Gateway }{NewHTTPGateway(server.URL)},
+	}
+	for name, gw := range gateways {
+		t.Run(name, func(t *testing.T) {
+			stream, err := OpenStream(context.Background(), gw, &inference.InferenceRequest{FunctionName: util.StringPtr("missing")})
+			var tzErr *tzerrors.TensorZeroError
+			require.ErrorAs(t, err, &tzErr)
+			assert.Equal(t, http.StatusBadRequest, tzErr.StatusCode)
+			assert.Nil(t, stream)
+		})
+	}
+}
+
+func TestStreamFromChannels(t *testing.T) {
+	chunkCh := make(chan inference.InferenceChunk, 1)
+	errCh := make(chan error, 1)
+	chunkCh <- &inference.JsonChunk{VariantName: "v1"}
+	close(chunkCh)
+	errCh <- errors.New("broken")
+	close(errCh)
+
+	canceled := false
+	stream := StreamFromChannels(chunkCh, errCh, func() { canceled = true })
+	require.True(t, stream.Next())
+	assert.Equal(t, "v1", stream.Current().GetVariantName())
+	assert.False(t, stream.Next())
+	assert.EqualError(t, stream.Err(), "broken")
+	assert.True(t, canceled)
+}
+
+func TestChainStream(t *testing.T) {
+	var ops []Operation
+	recorder := func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		ops = append(ops, call.Operation)
+		return next(ctx, call)
+	}
+
+	stream, err := OpenStream(context.Background(), Chain(newChainMockGateway(), recorder), &inference.InferenceRequest{})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	count := 0
+	for range stream.All() {
+		count++
+	}
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []Operation{OperationInferenceStream}, ops)
+}
@@ -25,7 +25,7 @@
 //
 // For streaming responses:
 //
-//	chunks, errs := client.InferenceStream(context.Background(), &tensorzero.InferenceRequest{
+//	stream, err := tensorzero.OpenStream(context.Background(), client, &tensorzero.InferenceRequest{
 //		Input: request.InferenceInput{
 //			Messages: []tensorzero.Message{
 //				{
@@ -37,22 +37,29 @@
 //			},
 //		},
 //		FunctionName: tensorzero.StringPtr("story_function"),
-//		Stream:       tensorzero.BoolPtr(true),
 //	})
+//	if err != nil {
+//		// Handle error
+//	}
+//	defer stream.Close()
 //
-//	for {
-//		select {
-//		case chunk, ok := <-chunks:
-//			if !ok {
-//				return // Stream finished
-//			}
-//			// Process chunk
-//		case err := <-errs:
-//			if err != nil {
-//				// Handle error
-//			}
+//	for chunk, err := range stream.All() {
+//		if err != nil {
+//			// Handle error
 //		}
+//		// Process chunk
 //	}
+//
+// InferenceStream offers the same stream as a pair of channels.
+//
+// NewPoolGateway distributes calls over several gateway replicas. A call that
+// could not be sent, because the connection failed or a circuit breaker
+// rejected it, always fails over to another replica. After transport errors
+// and 502, 503 or 504 responses only idempotent operations such as feedback
+// and listing fail over by default: inference calls are not idempotent and
+// fail over only after opting in with
+//
+//	tensorzero.WithFailoverOperations(tensorzero.OperationInference, tensorzero.OperationInferenceStream)
 package tensorzero
 
 const (
@@ -73,7 +80,10 @@ const (
 	RoleSystem    = "system"
 )
 
-// Tool choice constants
+// Tool choice constants.
+//
+// Deprecated: use tool.Auto, tool.Required, tool.None and tool.Specific, which
+// produce the tool choice values expected by the gateway.
 const (
 	ToolChoiceAuto     = "auto"
 	ToolChoiceRequired = "required"
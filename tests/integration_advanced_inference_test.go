@@ -192,7 +192,7 @@ func TestIntegration_AdvancedInferenceScenarios(t *testing.T) {
 				},
 			},
 			AdditionalTools: additionalTools,
-			ToolChoice: tool.ToolChoice("auto"),
+			ToolChoice: tool.Auto(),
 			Tags: map[string]string{
 				"test_type": "dynamic_tools",
 				"scenario": "tip_calculation",
@@ -204,9 +204,9 @@ func TestIntegration_AdvancedInferenceScenarios(t *testing.T) {
 
 	t.Run("InferenceWithCacheOptions", func(t *testing.T) {
 		// Test inference with caching enabled
-		cacheOptions := map[string]interface{}{
-			"enabled": "on",
-			"max_age_s": 3600, // 1 hour
+		cacheOptions := &inference.CacheOptions{
+			Enabled: inference.CacheModeOn,
+			MaxAge:  time.Hour,
 		}
 
 		// First request - should miss cache
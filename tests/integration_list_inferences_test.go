@@ -170,7 +170,7 @@ func TestIntegration_InferenceFiltering(t *testing.T) {
 		// Verify all have the expected metric value
 		for _, inf := range inferences {
 			if thumbsUp, exists := inf.MetricValues["thumbs_up"]; exists {
-				assert.Equal(t, true, thumbsUp)
+				assert.Equal(t, inference.BoolMetricValue(true), thumbsUp)
 			}
 		}
 	})
@@ -192,7 +192,7 @@ func TestIntegration_InferenceFiltering(t *testing.T) {
 		// Verify all have rating >= 3.0
 		for _, inf := range inferences {
 			if rating, exists := inf.MetricValues["rating"]; exists {
-				if ratingFloat, ok := rating.(float64); ok {
+				if ratingFloat, ok := rating.Float(); ok {
 					assert.GreaterOrEqual(t, ratingFloat, 3.0)
 				}
 			}
@@ -236,10 +236,10 @@ func TestIntegration_InferenceFiltering(t *testing.T) {
 		// Verify results match both conditions
 		for _, inf := range inferences {
 			if thumbsUp, exists := inf.MetricValues["thumbs_up"]; exists {
-				assert.Equal(t, true, thumbsUp)
+				assert.Equal(t, inference.BoolMetricValue(true), thumbsUp)
 			}
 			if rating, exists := inf.MetricValues["rating"]; exists {
-				if ratingFloat, ok := rating.(float64); ok {
+				if ratingFloat, ok := rating.Float(); ok {
 					assert.GreaterOrEqual(t, ratingFloat, 3.0)
 				}
 			}
@@ -267,13 +267,13 @@ func TestIntegration_InferenceFiltering(t *testing.T) {
 			matchesCondition := false
 			
 			if rating, exists := inf.MetricValues["rating"]; exists {
-				if ratingFloat, ok := rating.(float64); ok && ratingFloat >= 4.0 {
+				if ratingFloat, ok := rating.Float(); ok && ratingFloat >= 4.0 {
 					matchesCondition = true
 				}
 			}
 			
 			if thumbsUp, exists := inf.MetricValues["thumbs_up"]; exists {
-				if thumbsUpBool, ok := thumbsUp.(bool); ok && thumbsUpBool {
+				if thumbsUpBool, ok := thumbsUp.Bool(); ok && thumbsUpBool {
 					matchesCondition = true
 				}
 			}
@@ -297,7 +297,7 @@ func TestIntegration_InferenceFiltering(t *testing.T) {
 		// Verify none have thumbs_up = true
 		for _, inf := range inferences {
 			if thumbsUp, exists := inf.MetricValues["thumbs_up"]; exists {
-				if thumbsUpBool, ok := thumbsUp.(bool); ok {
+				if thumbsUpBool, ok := thumbsUp.Bool(); ok {
 					assert.False(t, thumbsUpBool, "Should not have thumbs_up = true with NOT filter")
 				}
 			}
@@ -330,8 +330,8 @@ func TestIntegration_InferenceFiltering(t *testing.T) {
 			// Check first condition: rating >= 3 AND thumbs_up = true
 			if rating, ratingExists := inf.MetricValues["rating"]; ratingExists {
 				if thumbsUp, thumbsExists := inf.MetricValues["thumbs_up"]; thumbsExists {
-					if ratingFloat, ok := rating.(float64); ok {
-						if thumbsUpBool, ok := thumbsUp.(bool); ok {
+					if ratingFloat, ok := rating.Float(); ok {
+						if thumbsUpBool, ok := thumbsUp.Bool(); ok {
 							if ratingFloat >= 3.0 && thumbsUpBool {
 								matchesCondition = true
 							}
@@ -342,7 +342,7 @@ func TestIntegration_InferenceFiltering(t *testing.T) {
 			
 			// Check second condition: rating = 1
 			if rating, exists := inf.MetricValues["rating"]; exists {
-				if ratingFloat, ok := rating.(float64); ok && ratingFloat == 1.0 {
+				if ratingFloat, ok := rating.Float(); ok && ratingFloat == 1.0 {
 					matchesCondition = true
 				}
 			}
@@ -0,0 +1,216 @@
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+)
+
+// Timeout kinds reported by TimeoutError. Every TimeoutError also matches errors.ErrTimeout.
+var (
+	// ErrConnectTimeout means no connection to the gateway could be established in time
+	ErrConnectTimeout = errors.New("connect timeout")
+
+	// ErrRequestTimeout means a unary call did not complete in time
+	ErrRequestTimeout = errors.New("request timeout")
+
+	// ErrFirstChunkTimeout means a stream did not deliver its first event in time
+	ErrFirstChunkTimeout = errors.New("time to first chunk exceeded")
+
+	// ErrIdleTimeout means a stream did not deliver its next event in time
+	ErrIdleTimeout = errors.New("stream idle timeout")
+)
+
+// TimeoutError is returned when a client-side deadline is exceeded
+type TimeoutError struct {
+	// Kind is one of ErrConnectTimeout, ErrRequestTimeout, ErrFirstChunkTimeout and ErrIdleTimeout.
+	Kind error
+
+	// Limit is the configured timeout, zero if it is the transport default.
+	Limit time.Duration
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Limit > 0 {
+		return fmt.Sprintf("%s after %s", e.Kind, e.Limit)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+	}
+	return e.Kind.Error()
+}
+
+// Unwrap lets errors.Is match the kind, errors.ErrTimeout and the underlying error
+func (e *TimeoutError) Unwrap() []error {
+	errs := []error{e.Kind, tzerrors.ErrTimeout}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// Timeout reports true so that the error behaves like a net.Error timeout
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// WithConnectTimeout limits the time to establish a connection to the gateway.
+// It applies to the default HTTP client and to custom clients whose transport
+// is nil or an *http.Transport; custom clients are copied, not modified.
+func WithConnectTimeout(timeout time.Duration) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.connectTimeout = timeout
+	}
+}
+
+// WithFirstChunkTimeout limits the time from starting a stream until its first
+// event arrives. Zero disables the limit.
+func WithFirstChunkTimeout(timeout time.Duration) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.firstChunkTimeout = timeout
+	}
+}
+
+// WithIdleTimeout limits the time between two events of a stream. Zero disables the limit.
+func WithIdleTimeout(timeout time.Duration) HTTPGatewayOption {
+	return func(g *httpGateway) {
+		g.idleTimeout = timeout
+	}
+}
+
+// withDialTimeout returns a copy of client whose transport gives up
+// connecting after timeout
+func withDialTimeout(client *http.Client, timeout time.Duration) *http.Client {
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return client
+	}
+
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+
+	c := *client
+	c.Transport = transport
+	return &c
+}
+
+// requestContext bounds a unary call by the gateway timeout
+func (g *httpGateway) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, g.timeout, &TimeoutError{Kind: ErrRequestTimeout, Limit: g.timeout})
+}
+
+// connectTimeoutError turns a dial timeout into a TimeoutError
+func (g *httpGateway) connectTimeoutError(err error) error {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout() {
+		return &TimeoutError{Kind: ErrConnectTimeout, Limit: g.connectTimeout, Err: err}
+	}
+	return err
+}
+
+// ownTimeout returns the TimeoutError that ended ctx, if ctx was ended by one of the client's deadlines
+func ownTimeout(ctx context.Context) *TimeoutError {
+	if ctx.Err() == nil {
+		return nil
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(context.Cause(ctx), &timeoutErr) {
+		return timeoutErr
+	}
+	return nil
+}
+
+// callerCanceled reports whether ctx was canceled by the caller rather than a client deadline
+func callerCanceled(ctx context.Context) bool {
+	return ctx.Err() != nil && ownTimeout(ctx) == nil
+}
+
+// timeoutCause replaces err with the client deadline that ended ctx, if any
+func timeoutCause(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if timeoutErr := ownTimeout(ctx); timeoutErr != nil {
+		return timeoutErr
+	}
+	return err
+}
+
+// streamWatchdog cancels a stream that exceeds its first chunk or idle timeout
+type streamWatchdog struct {
+	cancel      context.CancelCauseFunc
+	idleTimeout time.Duration
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+// watchStream returns a context that is canceled with a TimeoutError when the
+// first event or the next event of a stream does not arrive in time. The
+// returned function must be called once the stream has ended.
+func (g *httpGateway) watchStream(ctx context.Context) (context.Context, *streamWatchdog, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	w := &streamWatchdog{cancel: cancel, idleTimeout: g.idleTimeout}
+	w.arm(ErrFirstChunkTimeout, g.firstChunkTimeout)
+
+	return ctx, w, func() {
+		w.stop()
+		cancel(context.Canceled)
+	}
+}
+
+// arm starts the timer for the given kind of timeout, if it is enabled
+func (w *streamWatchdog) arm(kind error, limit time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.stopped || limit <= 0 {
+		return
+	}
+	w.timer = time.AfterFunc(limit, func() {
+		w.cancel(&TimeoutError{Kind: kind, Limit: limit})
+	})
+}
+
+// pause stops the timer while a received event is handed to the consumer
+func (w *streamWatchdog) pause() {
+	w.arm(ErrIdleTimeout, 0)
+}
+
+// feed restarts the idle timer after an event was handed to the consumer
+func (w *streamWatchdog) feed() {
+	w.arm(ErrIdleTimeout, w.idleTimeout)
+}
+
+// stop disarms the watchdog
+func (w *streamWatchdog) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stopped = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
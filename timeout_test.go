@@ -0,0 +1,169 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const streamEvent = "data: {\"inference_id\":\"550e8400-e29b-41d4-a716-446655440000\",\"episode_id\":\"550e8400-e29b-41d4-a716-446655440001\",\"variant_name\":\"v1\",\"raw\":\"{\"}\n\n"
+
+func TestRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(feedbackOK))
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL, WithTimeout(20*time.Millisecond))
+	_, err := client.Feedback(context.Background(), &feedback.Request{MetricName: "rating", Value: 1.0})
+
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.ErrorIs(t, err, ErrRequestTimeout)
+	assert.ErrorIs(t, err, tzerrors.ErrTimeout)
+	assert.Equal(t, 20*time.Millisecond, timeoutErr.Limit)
+}
+
+func TestCallerDeadlineIsNotRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(feedbackOK))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := NewHTTPGateway(server.URL).Feedback(ctx, &feedback.Request{MetricName: "rating", Value: 1.0})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, errors.Is(err, ErrRequestTimeout))
+}
+
+func TestStreamOutlivesRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			w.Write([]byte(streamEvent))
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL, WithTimeout(30*time.Millisecond))
+	chunks, errs := client.InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	count := 0
+	for range chunks {
+		count++
+	}
+	require.NoError(t, <-errs)
+	assert.Equal(t, 3, count)
+}
+
+func TestFirstChunkTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(streamEvent))
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL, WithFirstChunkTimeout(20*time.Millisecond))
+	chunks, errs := client.InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	for range chunks {
+		t.Fatal("unexpected chunk")
+	}
+	err := <-errs
+	assert.ErrorIs(t, err, ErrFirstChunkTimeout)
+	assert.ErrorIs(t, err, tzerrors.ErrTimeout)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(streamEvent))
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(streamEvent))
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL,
+		WithFirstChunkTimeout(time.Second),
+		WithIdleTimeout(20*time.Millisecond),
+	)
+	chunks, errs := client.InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	count := 0
+	for range chunks {
+		count++
+	}
+	err := <-errs
+	assert.Equal(t, 1, count)
+	assert.ErrorIs(t, err, ErrIdleTimeout)
+	assert.False(t, errors.Is(err, ErrFirstChunkTimeout))
+}
+
+func TestIdleTimeoutIgnoresSlowConsumer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 15; i++ {
+			w.Write([]byte(streamEvent))
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPGateway(server.URL, WithIdleTimeout(20*time.Millisecond))
+	chunks, errs := client.InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("json")})
+	count := 0
+	for range chunks {
+		if count == 0 {
+			// The chunk buffer fills up while the consumer is busy
+			time.Sleep(60 * time.Millisecond)
+		}
+		count++
+	}
+	require.NoError(t, <-errs)
+	assert.Equal(t, 15, count)
+}
+
+func TestConnectTimeoutError(t *testing.T) {
+	g := &httpGateway{connectTimeout: time.Second}
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: &timeoutNetError{}}
+
+	err := g.connectTimeoutError(dialErr)
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.ErrorIs(t, err, ErrConnectTimeout)
+	assert.Equal(t, time.Second, timeoutErr.Limit)
+
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: &timeoutNetError{}}
+	assert.Equal(t, error(readErr), g.connectTimeoutError(readErr))
+}
+
+func TestWithConnectTimeoutCopiesClient(t *testing.T) {
+	custom := &http.Client{}
+	client := NewHTTPGateway("http://localhost:3000", WithHTTPClient(custom), WithConnectTimeout(time.Second))
+
+	assert.Nil(t, custom.Transport)
+	assert.IsType(t, &http.Transport{}, client.(*httpGateway).httpClient.Transport)
+}
+
+type timeoutNetError struct{}
+
+func (e *timeoutNetError) Error() string   { return "i/o timeout" }
+func (e *timeoutNetError) Timeout() bool   { return true }
+func (e *timeoutNetError) Temporary() bool { return true }
@@ -0,0 +1,125 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+)
+
+// ToolChoiceMode is the strategy of a ToolChoice
+type ToolChoiceMode string
+
+const (
+	ToolChoiceModeAuto     ToolChoiceMode = "auto"
+	ToolChoiceModeRequired ToolChoiceMode = "required"
+	ToolChoiceModeNone     ToolChoiceMode = "none"
+	ToolChoiceModeSpecific ToolChoiceMode = "specific"
+)
+
+// ToolChoice selects how the model uses tools. Create it with Auto, Required,
+// None or Specific; the zero value leaves the choice to the function configuration.
+// It is encoded as "auto", "required", "none" or {"specific": "<tool name>"}.
+type ToolChoice struct {
+	mode ToolChoiceMode
+	name string
+}
+
+// Auto lets the model decide whether to call tools
+func Auto() ToolChoice {
+	return ToolChoice{mode: ToolChoiceModeAuto}
+}
+
+// Required makes the model call at least one tool
+func Required() ToolChoice {
+	return ToolChoice{mode: ToolChoiceModeRequired}
+}
+
+// None prevents the model from calling tools
+func None() ToolChoice {
+	return ToolChoice{mode: ToolChoiceModeNone}
+}
+
+// Specific makes the model call the tool with the given name
+func Specific(name string) ToolChoice {
+	return ToolChoice{mode: ToolChoiceModeSpecific, name: name}
+}
+
+// Mode returns the strategy, or "" for the zero value
+func (c ToolChoice) Mode() ToolChoiceMode {
+	return c.mode
+}
+
+// Name returns the tool name of a Specific choice
+func (c ToolChoice) Name() string {
+	return c.name
+}
+
+// IsZero reports whether no choice was made
+func (c ToolChoice) IsZero() bool {
+	return c.mode == ""
+}
+
+// String formats the choice as "auto", "required", "none" or "specific:<name>"
+func (c ToolChoice) String() string {
+	if c.mode == ToolChoiceModeSpecific {
+		return "specific:" + c.name
+	}
+	return string(c.mode)
+}
+
+// Validate checks a Specific choice against the tools of a request. The tool
+// must be one of allowedTools or additionalTools. If allowedTools is empty, all
+// configured tools are allowed and only an empty name is rejected.
+func (c ToolChoice) Validate(allowedTools, additionalTools []string) error {
+	if c.mode != ToolChoiceModeSpecific {
+		return nil
+	}
+	if c.name == "" {
+		return tzerrors.NewValidationError("tool_choice", "specific tool choice requires a tool name")
+	}
+	if len(allowedTools) == 0 || slices.Contains(allowedTools, c.name) || slices.Contains(additionalTools, c.name) {
+		return nil
+	}
+	return tzerrors.NewValidationError("tool_choice", fmt.Sprintf("tool %q is neither an allowed nor an additional tool", c.name))
+}
+
+// MarshalJSON encodes the choice in the wire format of the gateway
+func (c ToolChoice) MarshalJSON() ([]byte, error) {
+	switch c.mode {
+	case ToolChoiceModeAuto, ToolChoiceModeRequired, ToolChoiceModeNone:
+		return json.Marshal(string(c.mode))
+	case ToolChoiceModeSpecific:
+		return json.Marshal(map[string]string{"specific": c.name})
+	case "":
+		return []byte("null"), nil
+	}
+	return nil, fmt.Errorf("unknown tool choice mode: %s", c.mode)
+}
+
+// UnmarshalJSON decodes a choice in the wire format of the gateway
+func (c *ToolChoice) UnmarshalJSON(data []byte) error {
+	var mode *string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		if mode == nil {
+			*c = ToolChoice{}
+			return nil
+		}
+		switch choice := ToolChoiceMode(*mode); choice {
+		case ToolChoiceModeAuto, ToolChoiceModeRequired, ToolChoiceModeNone:
+			*c = ToolChoice{mode: choice}
+			return nil
+		}
+		return fmt.Errorf("unknown tool choice: %s", *mode)
+	}
+
+	var specific struct {
+		Specific *string `json:"specific"`
+	}
+	if err := json.Unmarshal(data, &specific); err != nil || specific.Specific == nil {
+		return fmt.Errorf("invalid tool choice: %s", data)
+	}
+	*c = Specific(*specific.Specific)
+	return nil
+}
@@ -0,0 +1,76 @@
+//go:build unit
+
+package tool
+
+import (
+	"encoding/json"
+	"testing"
+
+	tzerrors "github.com/denkhaus/tensorzero/errors"
+	"github.com/test-go/testify/assert"
+)
+
+func TestToolChoiceJSON(t *testing.T) {
+	tests := []struct {
+		choice ToolChoice
+		wire   string
+	}{
+		{Auto(), `"auto"`},
+		{Required(), `"required"`},
+		{None(), `"none"`},
+		{Specific("get_weather"), `{"specific":"get_weather"}`},
+		{ToolChoice{}, `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wire, func(t *testing.T) {
+			data, err := json.Marshal(tt.choice)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.wire, string(data))
+
+			var decoded ToolChoice
+			assert.NoError(t, json.Unmarshal(data, &decoded))
+			assert.Equal(t, tt.choice, decoded)
+		})
+	}
+}
+
+func TestToolChoiceInvalidJSON(t *testing.T) {
+	for _, data := range []string{`"off"`, `{}`, `{"specific":1}`, `42`, `["auto"]`} {
+		var choice ToolChoice
+		assert.Error(t, json.Unmarshal([]byte(data), &choice), data)
+	}
+}
+
+func TestToolChoiceOmitted(t *testing.T) {
+	data, err := json.Marshal(ToolParams{})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "tool_choice")
+
+	var params ToolParams
+	assert.NoError(t, json.Unmarshal([]byte(`{"tools_available":[],"tool_choice":{"specific":"search"}}`), &params))
+	assert.Equal(t, ToolChoiceModeSpecific, params.ToolChoice.Mode())
+	assert.Equal(t, "search", params.ToolChoice.Name())
+}
+
+func TestToolChoiceAccessors(t *testing.T) {
+	assert.True(t, ToolChoice{}.IsZero())
+	assert.False(t, None().IsZero())
+	assert.Equal(t, ToolChoiceModeRequired, Required().Mode())
+	assert.Equal(t, "auto", Auto().String())
+	assert.Equal(t, "specific:search", Specific("search").String())
+}
+
+func TestToolChoiceValidate(t *testing.T) {
+	assert.NoError(t, Auto().Validate(nil, nil))
+	assert.NoError(t, Specific("search").Validate(nil, nil))
+	assert.NoError(t, Specific("search").Validate([]string{"search"}, nil))
+	assert.NoError(t, Specific("lookup").Validate([]string{"search"}, []string{"lookup"}))
+
+	err := Specific("delete").Validate([]string{"search"}, []string{"lookup"})
+	validationErr, ok := err.(*tzerrors.ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, "tool_choice", validationErr.Field)
+
+	assert.Error(t, Specific("").Validate(nil, nil))
+}
@@ -11,9 +11,6 @@ type ToolCallChunk struct {
 func (tcc *ToolCallChunk) GetType() string { return tcc.Type }
 func (tcc *ToolCallChunk) GetID() string   { return tcc.ID }
 
-// ToolChoice represents tool choice options
-type ToolChoice interface{}
-
 // Tool represents a tool definition that can be called by AI models during inference.
 // Tools enable models to perform actions, retrieve information, or interact with external systems.
 type Tool struct {
@@ -43,7 +40,7 @@ type ToolParams struct {
 
 	// ToolChoice specifies the strategy for tool selection during inference.
 	// Options include "none", "auto", "required", or specific tool selection.
-	ToolChoice string `json:"tool_choice"`
+	ToolChoice ToolChoice `json:"tool_choice,omitzero"`
 
 	// ParallelToolCalls, when true, allows the model to request multiple tool calls
 	// in a single response. Only supported by certain model providers.
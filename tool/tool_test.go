@@ -37,11 +37,11 @@ func TestToolParams(t *testing.T) {
 	parallel := true
 	params := ToolParams{
 		ToolsAvailable:    []Tool{tool1, tool2},
-		ToolChoice:        "auto",
+		ToolChoice:        Auto(),
 		ParallelToolCalls: &parallel,
 	}
 	assert.Len(t, params.ToolsAvailable, 2)
-	assert.Equal(t, "auto", params.ToolChoice)
+	assert.Equal(t, Auto(), params.ToolChoice)
 	assert.NotNil(t, params.ParallelToolCalls)
 	assert.True(t, *params.ParallelToolCalls)
 }
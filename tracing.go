@@ -0,0 +1,155 @@
+package tensorzero
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/denkhaus/tensorzero/evaluation"
+	"github.com/denkhaus/tensorzero/feedback"
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/shared"
+	"github.com/denkhaus/tensorzero/tracing"
+)
+
+// TracingInterceptor creates a span for every gateway call. The span is stored
+// in the context passed down the chain, so the HTTP gateway propagates it to
+// the gateway with a traceparent header. Streaming spans stay open until the
+// last chunk and record the time to the first chunk.
+func TracingInterceptor(tracer tracing.Tracer) Interceptor {
+	return func(ctx context.Context, call *Call, next Invoker) (interface{}, error) {
+		ctx, span := tracer.Start(ctx, "tensorzero."+string(call.Operation))
+		ctx = tracing.ContextWithSpan(ctx, span)
+		span.SetAttributes(tracing.String(tracing.AttrOperation, string(call.Operation)))
+		span.SetAttributes(requestAttributes(call.Request)...)
+
+		start := time.Now()
+		resp, err := next(ctx, call)
+		if err != nil {
+			span.RecordError(err)
+			span.End()
+			return resp, err
+		}
+
+		stream, ok := resp.(*InferenceStreamResult)
+		if !ok {
+			span.SetAttributes(responseAttributes(resp)...)
+			span.End()
+			return resp, nil
+		}
+
+		var once sync.Once
+		var chunks int
+		return ObserveStream(ctx, stream,
+			func(chunk inference.InferenceChunk) {
+				once.Do(func() {
+					ttft := time.Since(start)
+					span.AddEvent(tracing.EventFirstChunk)
+					span.SetAttributes(tracing.Float64(tracing.AttrTimeToFirstToken, float64(ttft)/float64(time.Millisecond)))
+				})
+				chunks++
+				span.SetAttributes(responseAttributes(chunk)...)
+			},
+			func(err error) {
+				span.SetAttributes(tracing.Int(tracing.AttrStreamChunkCount, chunks))
+				if err != nil {
+					span.RecordError(err)
+				}
+				span.End()
+			},
+		), nil
+	}
+}
+
+// requestAttributes describes the typed request of a call
+func requestAttributes(req interface{}) []tracing.Attribute {
+	var attrs []tracing.Attribute
+	switch r := req.(type) {
+	case *inference.InferenceRequest:
+		if r.FunctionName != nil {
+			attrs = append(attrs, tracing.String(tracing.AttrFunctionName, *r.FunctionName))
+		}
+		if r.VariantName != nil {
+			attrs = append(attrs, tracing.String(tracing.AttrVariantName, *r.VariantName))
+		}
+		if r.EpisodeID != nil {
+			attrs = append(attrs, tracing.String(tracing.AttrEpisodeID, r.EpisodeID.String()))
+		}
+	case *feedback.Request:
+		attrs = append(attrs, tracing.String(tracing.AttrMetricName, r.MetricName))
+		if r.InferenceID != nil {
+			attrs = append(attrs, tracing.String(tracing.AttrInferenceID, r.InferenceID.String()))
+		}
+		if r.EpisodeID != nil {
+			attrs = append(attrs, tracing.String(tracing.AttrEpisodeID, r.EpisodeID.String()))
+		}
+	case *inference.ListInferencesRequest:
+		if r.FunctionName != nil {
+			attrs = append(attrs, tracing.String(tracing.AttrFunctionName, *r.FunctionName))
+		}
+	case *BulkInsertDatapointsRequest:
+		attrs = append(attrs, tracing.String(tracing.AttrDatasetName, r.DatasetName))
+	case *DeleteDatapointRequest:
+		attrs = append(attrs, tracing.String(tracing.AttrDatasetName, r.DatasetName))
+	}
+	return attrs
+}
+
+// responseAttributes describes a typed response or stream chunk
+func responseAttributes(resp interface{}) []tracing.Attribute {
+	var attrs []tracing.Attribute
+	switch r := resp.(type) {
+	case inference.InferenceResponse:
+		attrs = append(attrs,
+			tracing.String(tracing.AttrInferenceID, r.GetInferenceID().String()),
+			tracing.String(tracing.AttrEpisodeID, r.GetEpisodeID().String()),
+			tracing.String(tracing.AttrVariantName, r.GetVariantName()),
+			tracing.Int(tracing.AttrInputTokens, r.GetUsage().InputTokens),
+			tracing.Int(tracing.AttrOutputTokens, r.GetUsage().OutputTokens),
+		)
+		if r.GetFinishReason() != nil {
+			attrs = append(attrs, tracing.String(tracing.AttrFinishReason, string(*r.GetFinishReason())))
+		}
+	case *inference.ChatChunk:
+		attrs = append(attrs, chunkAttributes(r, r.Usage, r.FinishReason)...)
+	case *inference.JsonChunk:
+		attrs = append(attrs, chunkAttributes(r, r.Usage, r.FinishReason)...)
+	case *feedback.Response:
+		attrs = append(attrs, tracing.String(tracing.AttrFeedbackID, r.FeedbackID.String()))
+	case *evaluation.RunResponse:
+		attrs = append(attrs, tracing.String(tracing.AttrRunID, r.RunID.String()))
+	case *evaluation.EpisodeResponse:
+		attrs = append(attrs, tracing.String(tracing.AttrEpisodeID, r.EpisodeID.String()))
+	}
+	return attrs
+}
+
+func chunkAttributes(chunk inference.InferenceChunk, usage *shared.Usage, finishReason *inference.FinishReason) []tracing.Attribute {
+	attrs := []tracing.Attribute{
+		tracing.String(tracing.AttrInferenceID, chunk.GetInferenceID().String()),
+		tracing.String(tracing.AttrEpisodeID, chunk.GetEpisodeID().String()),
+		tracing.String(tracing.AttrVariantName, chunk.GetVariantName()),
+	}
+	if usage != nil {
+		attrs = append(attrs,
+			tracing.Int(tracing.AttrInputTokens, usage.InputTokens),
+			tracing.Int(tracing.AttrOutputTokens, usage.OutputTokens),
+		)
+	}
+	if finishReason != nil {
+		attrs = append(attrs, tracing.String(tracing.AttrFinishReason, string(*finishReason)))
+	}
+	return attrs
+}
+
+// injectTraceContext propagates the span in ctx to the gateway
+func injectTraceContext(ctx context.Context, header http.Header) {
+	span := tracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	if sc := span.SpanContext(); sc.IsValid() {
+		header.Set("traceparent", sc.TraceParent())
+	}
+}
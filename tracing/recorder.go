@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Recorder is an in-memory Tracer that keeps every span it creates.
+// It is intended for tests and offline debugging.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Start creates a recorded span
+func (r *Recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &RecordedSpan{
+		Name:       name,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]interface{}),
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.Parent = parent.SpanContext()
+		span.context.TraceID = span.Parent.TraceID
+	} else {
+		putRandom(span.context.TraceID[:])
+	}
+	putRandom(span.context.SpanID[:])
+	span.context.Sampled = true
+
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Spans returns all spans recorded so far
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedSpan(nil), r.spans...)
+}
+
+// Reset removes all recorded spans
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+// RecordedEvent is an event added to a recorded span
+type RecordedEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+// RecordedSpan is a span created by a Recorder
+type RecordedSpan struct {
+	Name       string
+	Parent     SpanContext
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]interface{}
+	Events     []RecordedEvent
+	Errors     []error
+
+	mu      sync.Mutex
+	context SpanContext
+	ended   bool
+}
+
+func (s *RecordedSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *RecordedSpan) AddEvent(name string, attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event := RecordedEvent{Name: name, Time: time.Now(), Attributes: make(map[string]interface{})}
+	for _, attr := range attrs {
+		event.Attributes[attr.Key] = attr.Value
+	}
+	s.Events = append(s.Events, event)
+}
+
+func (s *RecordedSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Errors = append(s.Errors, err)
+}
+
+func (s *RecordedSpan) SpanContext() SpanContext {
+	return s.context
+}
+
+func (s *RecordedSpan) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.ended = true
+		s.EndTime = time.Now()
+	}
+}
+
+// Ended reports whether End has been called
+func (s *RecordedSpan) Ended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ended
+}
+
+// Attribute returns the value of an attribute
+func (s *RecordedSpan) Attribute(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.Attributes[key]
+	return value, ok
+}
+
+func putRandom(b []byte) {
+	for i := range b {
+		b[i] = byte(rand.IntN(256))
+	}
+}
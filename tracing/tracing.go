@@ -0,0 +1,130 @@
+// Package tracing provides a minimal tracer abstraction for the TensorZero client.
+// Adapters for tracing libraries such as OpenTelemetry implement Tracer and Span;
+// Recorder is an in-memory implementation for tests.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Attribute keys recorded on gateway spans
+const (
+	AttrOperation        = "tensorzero.operation"
+	AttrFunctionName     = "tensorzero.function_name"
+	AttrVariantName      = "tensorzero.variant_name"
+	AttrInferenceID      = "tensorzero.inference_id"
+	AttrEpisodeID        = "tensorzero.episode_id"
+	AttrFeedbackID       = "tensorzero.feedback_id"
+	AttrRunID            = "tensorzero.run_id"
+	AttrMetricName       = "tensorzero.metric_name"
+	AttrDatasetName      = "tensorzero.dataset_name"
+	AttrFinishReason     = "tensorzero.finish_reason"
+	AttrInputTokens      = "tensorzero.usage.input_tokens"
+	AttrOutputTokens     = "tensorzero.usage.output_tokens"
+	AttrTimeToFirstToken = "tensorzero.stream.time_to_first_token_ms"
+	AttrStreamChunkCount = "tensorzero.stream.chunk_count"
+)
+
+// EventFirstChunk is added to a streaming span when the first chunk arrives
+const EventFirstChunk = "first_chunk"
+
+// Attribute is a key-value pair attached to a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String creates a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int creates an integer attribute
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Float64 creates a float attribute
+func Float64(key string, value float64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer creates spans
+type Tracer interface {
+	// Start creates a span that is a child of the span in ctx, if any, and
+	// returns a context carrying the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	AddEvent(name string, attrs ...Attribute)
+	RecordError(err error)
+	SpanContext() SpanContext
+	End()
+}
+
+// SpanContext identifies a span across process boundaries (W3C trace context)
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether both the trace ID and the span ID are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent formats the span context as a W3C traceparent header value
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceParent parses a W3C traceparent header value
+func ParseTraceParent(value string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("invalid traceparent: %q", value)
+	}
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("unsupported traceparent version: %q", value)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, fmt.Errorf("invalid trace ID: %w", err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, fmt.Errorf("invalid span ID: %w", err)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, fmt.Errorf("invalid trace flags: %w", err)
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent: %q", value)
+	}
+	return sc, nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a context carrying span
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanContextKey{}).(Span)
+	return span
+}
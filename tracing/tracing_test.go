@@ -0,0 +1,77 @@
+//go:build unit
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceParent(value)
+	require.NoError(t, err)
+	assert.True(t, sc.IsValid())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, value, sc.TraceParent())
+
+	sc.Sampled = false
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", sc.TraceParent())
+}
+
+func TestParseTraceParentInvalid(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, err := ParseTraceParent(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder()
+
+	ctx, parent := recorder.Start(context.Background(), "parent")
+	assert.Same(t, parent, SpanFromContext(ctx))
+
+	_, child := recorder.Start(ctx, "child")
+	child.SetAttributes(String("key", "value"), Int("count", 2))
+	child.AddEvent("something", Float64("latency", 1.5))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	child.End()
+	parent.End()
+
+	spans := recorder.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "parent", spans[0].Name)
+	assert.Equal(t, "child", spans[1].Name)
+	assert.True(t, spans[1].Ended())
+	assert.Equal(t, parent.SpanContext().TraceID, child.SpanContext().TraceID)
+	assert.Equal(t, parent.SpanContext(), spans[1].Parent)
+	assert.NotEqual(t, parent.SpanContext().SpanID, child.SpanContext().SpanID)
+
+	value, ok := spans[1].Attribute("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+	require.Len(t, spans[1].Events, 1)
+	assert.Equal(t, 1.5, spans[1].Events[0].Attributes["latency"])
+	assert.Len(t, spans[1].Errors, 1)
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Spans())
+}
+
+func TestSpanFromContextEmpty(t *testing.T) {
+	assert.Nil(t, SpanFromContext(context.Background()))
+}
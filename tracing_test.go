@@ -0,0 +1,121 @@
+//go:build unit
+
+package tensorzero
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/denkhaus/tensorzero/inference"
+	"github.com/denkhaus/tensorzero/tracing"
+	"github.com/denkhaus/tensorzero/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracingInterceptorInference(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{
+			"inference_id": "550e8400-e29b-41d4-a716-446655440000",
+			"episode_id": "550e8400-e29b-41d4-a716-446655440001",
+			"variant_name": "test_variant",
+			"content": [{"type": "text", "text": "Hello"}],
+			"usage": {"input_tokens": 10, "output_tokens": 5},
+			"finish_reason": "stop"
+		}`))
+	}))
+	defer server.Close()
+
+	recorder := tracing.NewRecorder()
+	client := Chain(NewHTTPGateway(server.URL), TracingInterceptor(recorder))
+
+	_, err := client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("my_function")})
+	require.NoError(t, err)
+
+	spans := recorder.Spans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "tensorzero.inference", span.Name)
+	assert.True(t, span.Ended())
+	assert.Equal(t, span.SpanContext().TraceParent(), traceparent)
+
+	expected := map[string]interface{}{
+		tracing.AttrFunctionName: "my_function",
+		tracing.AttrVariantName:  "test_variant",
+		tracing.AttrInferenceID:  "550e8400-e29b-41d4-a716-446655440000",
+		tracing.AttrEpisodeID:    "550e8400-e29b-41d4-a716-446655440001",
+		tracing.AttrInputTokens:  10,
+		tracing.AttrOutputTokens: 5,
+		tracing.AttrFinishReason: "stop",
+	}
+	for key, value := range expected {
+		actual, ok := span.Attribute(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, value, actual, key)
+	}
+}
+
+func TestTracingInterceptorError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Unknown function: nope"}`))
+	}))
+	defer server.Close()
+
+	recorder := tracing.NewRecorder()
+	client := Chain(NewHTTPGateway(server.URL), TracingInterceptor(recorder))
+
+	_, err := client.Inference(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("nope")})
+	require.Error(t, err)
+
+	spans := recorder.Spans()
+	require.Len(t, spans, 1)
+	assert.True(t, spans[0].Ended())
+	assert.Len(t, spans[0].Errors, 1)
+}
+
+func TestTracingInterceptorStream(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("traceparent"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"inference_id\":\"550e8400-e29b-41d4-a716-446655440000\",\"episode_id\":\"550e8400-e29b-41d4-a716-446655440001\",\"variant_name\":\"v1\",\"content\":[{\"type\":\"text\",\"id\":\"0\",\"text\":\"Hel\"}]}\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: {\"inference_id\":\"550e8400-e29b-41d4-a716-446655440000\",\"episode_id\":\"550e8400-e29b-41d4-a716-446655440001\",\"variant_name\":\"v1\",\"content\":[],\"usage\":{\"input_tokens\":3,\"output_tokens\":2},\"finish_reason\":\"stop\"}\n\n"))
+	}))
+	defer server.Close()
+
+	recorder := tracing.NewRecorder()
+	client := Chain(NewHTTPGateway(server.URL), TracingInterceptor(recorder))
+
+	chunks, errs := client.InferenceStream(context.Background(), &inference.InferenceRequest{FunctionName: util.StringPtr("story")})
+
+	<-chunks
+	spans := recorder.Spans()
+	require.Len(t, spans, 1)
+	assert.False(t, spans[0].Ended(), "span stays open while the stream is running")
+
+	close(release)
+	for range chunks {
+	}
+	require.NoError(t, <-errs)
+
+	require.Eventually(t, spans[0].Ended, time.Second, 10*time.Millisecond)
+	ttft, ok := spans[0].Attribute(tracing.AttrTimeToFirstToken)
+	assert.True(t, ok)
+	assert.Greater(t, ttft.(float64), 0.0)
+	count, _ := spans[0].Attribute(tracing.AttrStreamChunkCount)
+	assert.Equal(t, 2, count)
+	finish, _ := spans[0].Attribute(tracing.AttrFinishReason)
+	assert.Equal(t, "stop", finish)
+	outputTokens, _ := spans[0].Attribute(tracing.AttrOutputTokens)
+	assert.Equal(t, 2, outputTokens)
+	require.Len(t, spans[0].Events, 1)
+	assert.Equal(t, tracing.EventFirstChunk, spans[0].Events[0].Name)
+}